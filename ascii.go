@@ -0,0 +1,24 @@
+package webencodings
+
+import "unicode/utf8"
+
+// asciiMask has the high bit of every byte in a 64-bit word set
+const asciiMask = 0x8080808080808080
+
+// asciiPrefixLen returns the length of the longest all-ASCII prefix of s.
+// It checks eight bytes at a time, so that the UTF-8 and x-user-defined
+// decoders, the x-user-defined, UTF-16 and TextEncoder encoders, and the
+// IncrementalDecoder can handle runs of ASCII without decoding each rune.
+func asciiPrefixLen[T string | []byte](s T) int {
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		word := uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+			uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+		if word&asciiMask != 0 {
+			break
+		}
+	}
+	for ; i < len(s) && s[i] < utf8.RuneSelf; i++ {
+	}
+	return i
+}
@@ -0,0 +1,32 @@
+package webencodings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestASCIIPrefixLen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"abcdefgh", 8},
+		{"abcdefghijklmnopq", 17},
+		{"\x80", 0},
+		{"abcdefg\x80", 7},
+		{"abcdefgh\x80", 8},
+		{"abcdefghij\xffklmnop", 10},
+		{strings.Repeat("a", 100) + "é", 100},
+	}
+
+	for _, test := range tests {
+		if n := asciiPrefixLen(test.input); n != test.expected {
+			t.Errorf("asciiPrefixLen(%q) = %d, expected %d", test.input, n, test.expected)
+		}
+		if n := asciiPrefixLen([]byte(test.input)); n != test.expected {
+			t.Errorf("asciiPrefixLen([]byte(%q)) = %d, expected %d", test.input, n, test.expected)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package webencodings
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrUnsupportedBOM is returned by DiagnoseBOM for the byte order mark of an
+// encoding that the Encoding Standard does not support
+var ErrUnsupportedBOM = errors.New("webencodings: byte order mark of an unsupported encoding")
+
+// DiagnoseBOM identifies a byte order mark at the start of input, including
+// those of UTF-32 and UTF-7. It returns the BOM's encoding name and length,
+// or "" and 0 if there is none. Supported BOMs are reported as DetectBOM
+// would; UTF-32LE, UTF-32BE and UTF-7 BOMs are reported as "utf-32le",
+// "utf-32be" and "utf-7" together with ErrUnsupportedBOM, so that callers can
+// reject such input rather than decode it as UTF-16 or ASCII.
+//
+// This is a diagnostic and not part of any spec. In particular FF FE 00 00
+// is taken to be UTF-32LE, though it is also a UTF-16LE BOM followed by
+// U+0000; DetectBOM and Decode keep treating it as UTF-16LE.
+func DiagnoseBOM(input []byte) (name string, length int, err error) {
+	switch {
+	case bytes.HasPrefix(input, []byte{0xFF, 0xFE, 0x00, 0x00}):
+		return "utf-32le", 4, ErrUnsupportedBOM
+	case bytes.HasPrefix(input, []byte{0x00, 0x00, 0xFE, 0xFF}):
+		return "utf-32be", 4, ErrUnsupportedBOM
+	case bytes.HasPrefix(input, []byte("+/v8-")):
+		return "utf-7", 5, ErrUnsupportedBOM
+	case len(input) >= 4 && bytes.HasPrefix(input, []byte("+/v")) && bytes.IndexByte([]byte("89+/"), input[3]) >= 0:
+		return "utf-7", 4, ErrUnsupportedBOM
+	}
+
+	encoding, remaining := DetectBOM(input)
+	if encoding == nil {
+		return "", 0, nil
+	}
+	return encoding.Name, len(input) - len(remaining), nil
+}
@@ -0,0 +1,41 @@
+package webencodings
+
+import "testing"
+
+func TestDiagnoseBOM(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		length int
+		err    error
+	}{
+		{"\xEF\xBB\xBFabc", "utf-8", 3, nil},
+		{"\xFF\xFEa\x00", "utf-16le", 2, nil},
+		{"\xFE\xFF\x00a", "utf-16be", 2, nil},
+		{"\xFF\xFE\x00\x00a\x00\x00\x00", "utf-32le", 4, ErrUnsupportedBOM},
+		{"\x00\x00\xFE\xFF\x00\x00\x00a", "utf-32be", 4, ErrUnsupportedBOM},
+		{"+/v8-abc", "utf-7", 5, ErrUnsupportedBOM},
+		{"+/v8abc", "utf-7", 4, ErrUnsupportedBOM},
+		{"+/v9", "utf-7", 4, ErrUnsupportedBOM},
+		{"+/v+", "utf-7", 4, ErrUnsupportedBOM},
+		{"+/v/", "utf-7", 4, ErrUnsupportedBOM},
+		{"+/va", "", 0, nil},
+		{"+/v", "", 0, nil},
+		{"\xFF\xFE\x00", "utf-16le", 2, nil},
+		{"\x00\x00\xFE", "", 0, nil},
+		{"abc", "", 0, nil},
+		{"", "", 0, nil},
+	}
+
+	for _, test := range tests {
+		name, length, err := DiagnoseBOM([]byte(test.input))
+		if name != test.name || length != test.length || err != test.err {
+			t.Errorf("DiagnoseBOM(%q): expected %q, %d, %v, got %q, %d, %v", test.input, test.name, test.length, test.err, name, length, err)
+		}
+	}
+
+	// DetectBOM keeps the Encoding Standard's behaviour
+	if encoding, _ := DetectBOM([]byte("\xFF\xFE\x00\x00")); encoding != utf16LE {
+		t.Errorf("DetectBOM: expected FF FE 00 00 to be UTF-16LE, got %v", encoding)
+	}
+}
@@ -0,0 +1,96 @@
+package webencodings
+
+// EncodingChange is the outcome of IncrementalDecoder.ChangeEncoding
+type EncodingChange int
+
+const (
+	// EncodingUnchanged means the decoder keeps its encoding, which is now
+	// Certain: either the new encoding is the same, or the decoder had
+	// already become certain of its encoding
+	EncodingUnchanged EncodingChange = iota
+	// EncodingSwitched means the decoder switched to the new encoding on the
+	// fly, and the text it has already returned is still valid
+	EncodingSwitched
+	// EncodingRestart means the input seen so far would decode differently,
+	// so decoding must start over with the new encoding
+	EncodingRestart
+)
+
+// String returns a short lower case name for the change
+func (c EncodingChange) String() string {
+	switch c {
+	case EncodingUnchanged:
+		return "unchanged"
+	case EncodingSwitched:
+		return "switched"
+	case EncodingRestart:
+		return "restart"
+	}
+	return "unknown"
+}
+
+// asciiCompatible reports whether encoding decodes every ASCII byte to the
+// same code point, without any state that an ASCII byte could change
+func asciiCompatible(encoding *EncodingInfo) bool {
+	switch encoding.Name {
+	case "utf-16le", "utf-16be", "iso-2022-jp", "replacement":
+		return false
+	}
+	return true
+}
+
+// ChangeEncoding implements the HTML standard's "change the encoding" for a
+// <meta> declaration found after decoding has begun. It returns how the
+// change was handled and the encoding to restart with, if a restart is
+// needed. UTF-16 is taken to mean UTF-8 and x-user-defined windows-1252,
+// as in the prescan.
+//
+// The decoder only switches when everything it has decoded so far is ASCII
+// and both encodings are ASCII-compatible, so that the text already returned
+// would be the same either way. Otherwise it keeps its encoding and returns
+// EncodingRestart; the caller should discard its output and decode the input
+// again with the returned encoding. An encoding that this package has no
+// codec for, such as koi8-r, fails with ErrUnsupportedEncoding and leaves
+// the decoder as it was, though the encoding is still returned.
+func (d *IncrementalDecoder) ChangeEncoding(encoding interface{}) (EncodingChange, *EncodingInfo, error) {
+	next, err := getEncoding(encoding)
+	if err != nil {
+		return EncodingUnchanged, nil, err
+	}
+	switch next.Name {
+	case "utf-16be", "utf-16le":
+		next = UTF8
+	case "x-user-defined":
+		next = Lookup("windows-1252")
+	}
+
+	if d.decoder == nil {
+		if d.Confidence == Certain {
+			return EncodingUnchanged, d.fallbackEncoding, nil
+		}
+		if !hasCodec(next) {
+			return EncodingUnchanged, next, ErrUnsupportedEncoding
+		}
+		// Nothing decoded yet, though a BOM still takes precedence
+		d.fallbackEncoding = next
+		d.Confidence = Certain
+		return EncodingSwitched, next, nil
+	}
+
+	if d.Confidence == Certain || next == d.Encoding || d.Encoding == utf16LE || d.Encoding == utf16BE {
+		d.Confidence = Certain
+		return EncodingUnchanged, d.Encoding, nil
+	}
+
+	if !hasCodec(next) {
+		return EncodingUnchanged, next, ErrUnsupportedEncoding
+	}
+	if d.nonASCII || !asciiCompatible(d.Encoding) || !asciiCompatible(next) {
+		return EncodingRestart, next, nil
+	}
+
+	d.decoder = newChunkDecoder(next, d.errors)
+	d.Encoding = next
+	d.Confidence = Certain
+	return EncodingSwitched, next, nil
+}
@@ -0,0 +1,79 @@
+package webencodings
+
+import "testing"
+
+func TestChangeEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		input    string
+		change   string
+		expected EncodingChange
+		encoding string
+		err      error
+	}{
+		{"ASCII prefix", "windows-1252", "<html><head>", "utf-8", EncodingSwitched, "utf-8", nil},
+		{"non-ASCII prefix", "windows-1252", "<title>\xe9</title>", "utf-8", EncodingRestart, "utf-8", nil},
+		{"same encoding", "windows-1252", "<title>\xe9</title>", "latin1", EncodingUnchanged, "windows-1252", nil},
+		{"UTF-16 means UTF-8", "windows-1252", "<html>", "utf-16le", EncodingSwitched, "utf-8", nil},
+		{"x-user-defined means windows-1252", "koi8-r", "<html>", "x-user-defined", EncodingUnchanged, "windows-1252", ErrUnsupportedEncoding},
+		{"BOM is certain", "windows-1252", "\xEF\xBB\xBF<html>", "koi8-r", EncodingUnchanged, "utf-8", nil},
+		{"UTF-16 is kept", "utf-16le", "<\x00h\x00", "koi8-r", EncodingUnchanged, "utf-16le", nil},
+		{"from iso-2022-jp", "iso-2022-jp", "<html>", "utf-8", EncodingRestart, "utf-8", nil},
+		{"nothing decoded", "windows-1252", "", "utf-8", EncodingSwitched, "utf-8", nil},
+		{"no codec", "windows-1252", "<html>", "koi8-r", EncodingUnchanged, "koi8-r", ErrUnsupportedEncoding},
+		{"no codec, non-ASCII prefix", "windows-1252", "<title>\xe9</title>", "shift_jis", EncodingUnchanged, "shift_jis", ErrUnsupportedEncoding},
+		{"no codec, nothing decoded", "utf-8", "", "koi8-r", EncodingUnchanged, "koi8-r", ErrUnsupportedEncoding},
+	}
+
+	for _, test := range tests {
+		decoder, err := NewIncrementalDecoder(test.fallback, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.input != "" {
+			decoder.Decode([]byte(test.input), false)
+		}
+
+		before := decoder.Encoding
+		change, encoding, err := decoder.ChangeEncoding(test.change)
+		if err != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+		if change != test.expected || encoding.Name != test.encoding {
+			t.Errorf("%s: expected %s to %s, got %s to %s", test.name, test.expected, test.encoding, change, encoding.Name)
+		}
+		if err != nil {
+			if decoder.Encoding != before || decoder.Confidence != Tentative {
+				t.Errorf("%s: expected a refused change to leave the decoder alone", test.name)
+			}
+		} else if change != EncodingRestart && decoder.Confidence != Certain {
+			t.Errorf("%s: expected certain confidence after the change", test.name)
+		}
+	}
+}
+
+func TestChangeEncodingSwitchesDecoder(t *testing.T) {
+	decoder, _ := NewIncrementalDecoder("windows-1252", "")
+	if decoder.Confidence != Tentative {
+		t.Errorf("expected a fallback encoding to be tentative")
+	}
+	first, _ := decoder.Decode([]byte("<meta charset=utf-8>"), false)
+
+	if change, _, _ := decoder.ChangeEncoding("utf-8"); change != EncodingSwitched {
+		t.Fatalf("expected the decoder to switch, got %s", change)
+	}
+	rest, _ := decoder.Decode([]byte("caf\xc3\xa9"), true)
+	if first+rest != "<meta charset=utf-8>café" || decoder.Encoding != UTF8 {
+		t.Errorf("unexpected output %q with %v", first+rest, decoder.Encoding)
+	}
+
+	// Once certain, later declarations are ignored
+	if change, encoding, _ := decoder.ChangeEncoding("koi8-r"); change != EncodingUnchanged || encoding != UTF8 {
+		t.Errorf("expected a certain decoder to keep UTF-8, got %s to %v", change, encoding)
+	}
+
+	if _, _, err := decoder.ChangeEncoding("bogus"); err != ErrUnknownEncoding {
+		t.Errorf("expected ErrUnknownEncoding, got %v", err)
+	}
+}
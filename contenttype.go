@@ -0,0 +1,211 @@
+package webencodings
+
+import "strings"
+
+// isHTTPSpace reports whether b is HTTP whitespace as the Fetch standard defines it
+func isHTTPSpace(b byte) bool {
+	return b == '\t' || b == '\n' || b == '\r' || b == ' '
+}
+
+// isHTTPToken reports whether s is non-empty and consists only of HTTP token code points
+func isHTTPToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if !isASCIIAlpha(b) && !(b >= '0' && b <= '9') && !strings.ContainsRune("!#$%&'*+-.^_`|~", rune(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHTTPQuotedStringToken reports whether s consists only of HTTP
+// quoted-string token code points
+func isHTTPQuotedStringToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b != '\t' && (b < 0x20 || b == 0x7f) {
+			return false
+		}
+	}
+	return true
+}
+
+// mimeType is a parsed MIME type with its lower cased essence and parameters
+type mimeType struct {
+	essence string
+	params  map[string]string
+}
+
+// parseMIMEType implements the MIME Sniffing standard's "parse a MIME type".
+// It returns nil on failure.
+func parseMIMEType(s string) *mimeType {
+	s = strings.Trim(s, "\t\n\r ")
+
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 || !isHTTPToken(s[:slash]) {
+		return nil
+	}
+	typ := s[:slash]
+	s = s[slash+1:]
+
+	end := strings.IndexByte(s, ';')
+	if end < 0 {
+		end = len(s)
+	}
+	subtype := strings.TrimRight(s[:end], "\t\n\r ")
+	if !isHTTPToken(subtype) {
+		return nil
+	}
+	s = s[end:]
+
+	mt := &mimeType{
+		essence: ASCIILower(typ + "/" + subtype),
+		params:  make(map[string]string),
+	}
+
+	for len(s) > 0 {
+		// Skip the ";" and any whitespace after it
+		s = strings.TrimLeft(s[1:], "\t\n\r ")
+
+		end := strings.IndexAny(s, ";=")
+		if end < 0 {
+			end = len(s)
+		}
+		name := ASCIILower(s[:end])
+		s = s[end:]
+		if len(s) > 0 {
+			if s[0] == ';' {
+				continue
+			}
+			s = s[1:]
+		}
+		if len(s) == 0 {
+			break
+		}
+
+		var value string
+		if s[0] == '"' {
+			value, s = collectHTTPQuotedString(s)
+			if end := strings.IndexByte(s, ';'); end >= 0 {
+				s = s[end:]
+			} else {
+				s = ""
+			}
+		} else {
+			end := strings.IndexByte(s, ';')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimRight(s[:end], "\t\n\r ")
+			s = s[end:]
+			if value == "" {
+				continue
+			}
+		}
+
+		if _, exists := mt.params[name]; !exists && isHTTPToken(name) && isHTTPQuotedStringToken(value) {
+			mt.params[name] = value
+		}
+	}
+
+	return mt
+}
+
+// collectHTTPQuotedString implements the Fetch standard's "collect an HTTP
+// quoted string" with the extract-value flag set. s must start with a
+// double quote. It returns the unescaped value and the unconsumed input.
+func collectHTTPQuotedString(s string) (string, string) {
+	var value strings.Builder
+	s = s[1:]
+	for {
+		end := strings.IndexAny(s, "\"\\")
+		if end < 0 {
+			value.WriteString(s)
+			return value.String(), ""
+		}
+		value.WriteString(s[:end])
+		quoteOrBackslash := s[end]
+		s = s[end+1:]
+		if quoteOrBackslash == '"' {
+			return value.String(), s
+		}
+		if len(s) == 0 {
+			value.WriteByte('\\')
+			return value.String(), ""
+		}
+		value.WriteByte(s[0])
+		s = s[1:]
+	}
+}
+
+// splitHeaderValues splits a combined header value on commas that are not
+// inside quoted strings, as in the Fetch standard's "get, decode, and split"
+func splitHeaderValues(s string) []string {
+	var values []string
+	var value strings.Builder
+	for {
+		end := strings.IndexAny(s, "\",")
+		if end < 0 {
+			value.WriteString(s)
+			break
+		}
+		value.WriteString(s[:end])
+		s = s[end:]
+		if s[0] == '"' {
+			quoted := len(s)
+			_, rest := collectHTTPQuotedString(s)
+			value.WriteString(s[:quoted-len(rest)])
+			s = rest
+			if len(s) > 0 {
+				continue
+			}
+			break
+		}
+		values = append(values, strings.Trim(value.String(), "\t "))
+		value.Reset()
+		s = s[1:]
+	}
+	return append(values, strings.Trim(value.String(), "\t "))
+}
+
+// ContentTypeEncoding returns the encoding named by the charset parameter
+// of a Content-Type header value, such as `text/html; charset="Shift_JIS"`.
+// It follows the Fetch standard's "extract a MIME type", so combined header
+// values and repeated parameters resolve as they do in browsers. An encoding
+// found this way comes from the transport layer and is Certain; if there is
+// no usable charset the result is nil and Tentative.
+func ContentTypeEncoding(contentType string) (*EncodingInfo, Confidence) {
+	var charset, essence string
+	var hasCharset bool
+	var mt *mimeType
+
+	for _, value := range splitHeaderValues(contentType) {
+		temp := parseMIMEType(value)
+		if temp == nil || temp.essence == "*/*" {
+			continue
+		}
+		mt = temp
+
+		if mt.essence != essence {
+			charset, hasCharset = mt.params["charset"]
+			essence = mt.essence
+		} else if _, ok := mt.params["charset"]; !ok && hasCharset {
+			mt.params["charset"] = charset
+		}
+	}
+
+	if mt == nil {
+		return nil, Tentative
+	}
+	label, ok := mt.params["charset"]
+	if !ok {
+		return nil, Tentative
+	}
+	encoding := Lookup(label)
+	if encoding == nil {
+		return nil, Tentative
+	}
+	return encoding, Certain
+}
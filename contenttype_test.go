@@ -0,0 +1,82 @@
+package webencodings
+
+import "testing"
+
+func TestContentTypeEncoding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`text/html; charset=utf-8`, "utf-8"},
+		{`text/html; charset="Shift_JIS"`, "shift_jis"},
+		{`TEXT/HTML;CHARSET=KOI8-R`, "koi8-r"},
+		{`  text/html ;  charset=gbk  `, "gbk"},
+		{`text/html; charset="big\5"`, "big5"},
+		{`text/html; charset="gbk";foo=bar`, "gbk"},
+		{`text/html; charset="gbk" trailing; foo=bar`, "gbk"},
+		{`text/html; charset=koi8-r; charset=big5`, "koi8-r"},
+		{`text/html; charset=; charset=big5`, "big5"},
+		{`text/html; foo; charset=big5`, "big5"},
+		{`text/html; charset=x-user-defined`, "x-user-defined"},
+
+		// Combined header values
+		{`text/plain;charset=gbk, text/html`, ""},
+		{`text/html;charset=gbk, text/html`, "gbk"},
+		{`text/html;charset=gbk, */*`, "gbk"},
+		{`text/html;charset=gbk, text/html;charset=big5`, "big5"},
+		{`text/html;x=",", text/html;charset=koi8-r`, "koi8-r"},
+		{`text/html;charset="a,b", text/html`, ""},
+		{`text/html;charset=big5, invalid`, "big5"},
+
+		// No usable charset
+		{``, ""},
+		{`text/html`, ""},
+		{`text/html; charset=bogus`, ""},
+		{`text /html; charset=big5`, ""},
+		{`text/; charset=big5`, ""},
+		{`charset=big5`, ""},
+	}
+
+	for _, test := range tests {
+		encoding, confidence := ContentTypeEncoding(test.input)
+		if test.expected == "" {
+			if encoding != nil || confidence != Tentative {
+				t.Errorf("ContentTypeEncoding(%q) = %v, %v, expected nil, tentative", test.input, encoding, confidence)
+			}
+		} else if encoding == nil || encoding.Name != test.expected || confidence != Certain {
+			t.Errorf("ContentTypeEncoding(%q) = %v, %v, expected %s, certain", test.input, encoding, confidence, test.expected)
+		}
+	}
+}
+
+func TestParseMIMEType(t *testing.T) {
+	mt := parseMIMEType(`Text/HTML; Foo="b\"ar"; baz=qux ;foo=ignored; é=1`)
+	if mt == nil {
+		t.Fatal("parseMIMEType failed")
+	}
+	if mt.essence != "text/html" {
+		t.Errorf("Expected essence text/html, got %s", mt.essence)
+	}
+	expected := map[string]string{"foo": `b"ar`, "baz": "qux"}
+	if len(mt.params) != len(expected) {
+		t.Errorf("Expected parameters %v, got %v", expected, mt.params)
+	}
+	for name, value := range expected {
+		if mt.params[name] != value {
+			t.Errorf("Expected parameter %s=%q, got %q", name, value, mt.params[name])
+		}
+	}
+}
+
+func TestSplitHeaderValues(t *testing.T) {
+	values := splitHeaderValues(`a, "b,c" d ,e,`)
+	expected := []string{"a", `"b,c" d`, "e", ""}
+	if len(values) != len(expected) {
+		t.Fatalf("Expected %q, got %q", expected, values)
+	}
+	for i := range values {
+		if values[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected, values)
+		}
+	}
+}
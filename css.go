@@ -0,0 +1,73 @@
+package webencodings
+
+import "bytes"
+
+// cssCharsetPrefix is the exact byte sequence a @charset rule starts with
+var cssCharsetPrefix = []byte(`@charset "`)
+
+// cssCharsetRule returns the encoding named by a @charset rule at the very
+// start of input, matched byte for byte as CSS Syntax Level 3 requires
+func cssCharsetRule(input []byte) *EncodingInfo {
+	if len(input) > 1024 {
+		input = input[:1024]
+	}
+	if !bytes.HasPrefix(input, cssCharsetPrefix) {
+		return nil
+	}
+
+	rest := input[len(cssCharsetPrefix):]
+	end := bytes.IndexByte(rest, '"')
+	if end < 0 || end+1 >= len(rest) || rest[end+1] != ';' {
+		return nil
+	}
+
+	encoding := Lookup(string(rest[:end]))
+	if encoding == utf16LE || encoding == utf16BE {
+		return UTF8
+	}
+	return encoding
+}
+
+// DecodeCSS decodes a stylesheet, determining its encoding as CSS Syntax
+// Level 3 describes: BOM, then the protocol encoding (such as a Content-Type
+// charset), then a @charset rule, then the environment encoding (that of the
+// referring document), then UTF-8. protocolEncoding and environmentEncoding
+// may be nil or "" when there is none; unknown labels are ignored. An
+// encoding that this package has no codec for, such as koi8-r, fails with
+// ErrUnsupportedEncoding, though the encoding is still returned.
+func DecodeCSS(input []byte, protocolEncoding interface{}, environmentEncoding interface{}, errors string) (string, *EncodingInfo, error) {
+	decoded, result, err := DecodeCSSWithResult(input, protocolEncoding, environmentEncoding, errors)
+	return decoded, result.Encoding, err
+}
+
+// DecodeCSSWithResult is like DecodeCSS but reports which step chose the
+// encoding. The environment encoding and the UTF-8 default are both
+// reported as SourceFallback. The result is returned alongside
+// ErrUnsupportedEncoding too.
+func DecodeCSSWithResult(input []byte, protocolEncoding interface{}, environmentEncoding interface{}, errors string) (string, *DecodeResult, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	result, remaining := sniffBOM(input)
+	if result == nil && protocolEncoding != nil && protocolEncoding != "" {
+		if encoding, _ := getEncoding(protocolEncoding); encoding != nil {
+			result = newDecodeResult(encoding, SourceTransport)
+		}
+	}
+	if result == nil {
+		if encoding := cssCharsetRule(input); encoding != nil {
+			result = newDecodeResult(encoding, SourceCSSCharset)
+		}
+	}
+	if result == nil && environmentEncoding != nil && environmentEncoding != "" {
+		if encoding, _ := getEncoding(environmentEncoding); encoding != nil {
+			result = newDecodeResult(encoding, SourceFallback)
+		}
+	}
+	if result == nil {
+		result = newDecodeResult(UTF8, SourceFallback)
+	}
+
+	return decodeWithResult(remaining, result, errors)
+}
@@ -0,0 +1,54 @@
+package webencodings
+
+import "testing"
+
+func TestDecodeCSS(t *testing.T) {
+	tests := []struct {
+		input       string
+		protocol    interface{}
+		environment interface{}
+		expected    string
+		err         error
+	}{
+		// BOM beats everything
+		{"\xef\xbb\xbf@charset \"koi8-r\";", "big5", "gbk", "utf-8", nil},
+		{"\xfe\xff", "big5", nil, "utf-16be", nil},
+		// Protocol beats @charset
+		{`@charset "koi8-r";`, "big5", "gbk", "big5", ErrUnsupportedEncoding},
+		{`@charset "koi8-r";`, Lookup("big5"), nil, "big5", ErrUnsupportedEncoding},
+		// @charset beats the environment
+		{`@charset "koi8-r"; a{}`, nil, "gbk", "koi8-r", ErrUnsupportedEncoding},
+		{`@charset "bogus"; a{}`, "", "gbk", "gbk", ErrUnsupportedEncoding},
+		{`@charset "koi8-r";`, "bogus", nil, "koi8-r", ErrUnsupportedEncoding},
+		{`@charset "utf-16";`, nil, nil, "utf-8", nil},
+		{`@charset "x-user-defined";`, nil, nil, "x-user-defined", nil},
+		// @charset must match exactly
+		{`@CHARSET "koi8-r";`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		{`@charset 'koi8-r';`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		{`@charset  "koi8-r";`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		{`@charset "koi8-r" ;`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		{` @charset "koi8-r";`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		{`@charset "koi8-r"`, nil, "gbk", "gbk", ErrUnsupportedEncoding},
+		// Otherwise UTF-8
+		{`a{}`, nil, nil, "utf-8", nil},
+		{`a{}`, nil, "bogus", "utf-8", nil},
+	}
+
+	for _, test := range tests {
+		_, encoding, err := DecodeCSS([]byte(test.input), test.protocol, test.environment, "")
+		if err != test.err {
+			t.Errorf("DecodeCSS(%q): expected error %v, got %v", test.input, test.err, err)
+		}
+		if encoding.Name != test.expected {
+			t.Errorf("DecodeCSS(%q, %v, %v): expected %s, got %s", test.input, test.protocol, test.environment, test.expected, encoding.Name)
+		}
+	}
+
+	decoded, _, err := DecodeCSS([]byte("\xef\xbb\xbfa{}"), nil, nil, "")
+	if err != nil {
+		t.Fatalf("DecodeCSS failed: %v", err)
+	}
+	if decoded != "a{}" {
+		t.Errorf("Expected %q, got %q", "a{}", decoded)
+	}
+}
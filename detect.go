@@ -0,0 +1,542 @@
+package webencodings
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Guess is a candidate encoding ranked by DetectEncoding
+type Guess struct {
+	// Encoding is the candidate encoding
+	Encoding *EncodingInfo
+	// Confidence is between 0 (no support) and 1 (strong support)
+	Confidence float64
+}
+
+// singleByteModel describes the high half of a legacy single-byte encoding
+// as its language's letters. All fields list bytes from 0x80 to 0xFF.
+type singleByteModel struct {
+	name string
+	// frequent holds the language's common non-ASCII letters, most frequent first
+	frequent string
+	// upper holds the other upper case letters
+	upper string
+	// letters holds the other lower case and uncased letters
+	letters string
+	// invalid holds bytes that decode to C1 controls or nothing at all
+	invalid string
+}
+
+// singleByteModels are the single-byte candidates of DetectEncoding, in
+// order of preference when they score the same
+var singleByteModels = []singleByteModel{
+	{
+		name: "windows-1252",
+		// éàèçêüöäßñáíóúãõâôîûëï
+		frequent: "\xe9\xe0\xe8\xe7\xea\xfc\xf6\xe4\xdf\xf1\xe1\xed\xf3\xfa\xe3\xf5\xe2\xf4\xee\xfb\xeb\xef",
+		// ŠŒŽŸÀÁÂÃÄÅÆÇÈÉÊËÌÍÎÏÐÑÒÓÔÕÖØÙÚÛÜÝÞ
+		upper: "\x8a\x8c\x8e\x9f\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd2\xd3\xd4\xd5\xd6\xd8\xd9\xda\xdb\xdc\xdd\xde",
+		// ƒˆšœžªµºåæìðòøùýþÿ
+		letters: "\x83\x88\x9a\x9c\x9e\xaa\xb5\xba\xe5\xe6\xec\xf0\xf2\xf8\xf9\xfd\xfe\xff",
+		invalid: "\x81\x8d\x8f\x90\x9d",
+	},
+	{
+		name: "windows-1250",
+		// áéíóúýčřšžěůąęłśćżźńőűöüä
+		frequent: "\xe1\xe9\xed\xf3\xfa\xfd\xe8\xf8\x9a\x9e\xec\xf9\xb9\xea\xb3\x9c\xe6\xbf\x9f\xf1\xf5\xfb\xf6\xfc\xe4",
+		// ŠŚŤŽŹŁĄŞŻĽŔÁÂĂÄĹĆÇČÉĘËĚÍÎĎĐŃŇÓÔŐÖŘŮÚŰÜÝŢ
+		upper: "\x8a\x8c\x8d\x8e\x8f\xa3\xa5\xaa\xaf\xbc\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd2\xd3\xd4\xd5\xd6\xd8\xd9\xda\xdb\xdc\xdd\xde",
+		// ťˇµşľßŕâăĺçëîďđňôţ
+		letters: "\x9d\xa1\xb5\xba\xbe\xdf\xe0\xe2\xe3\xe5\xe7\xeb\xee\xef\xf0\xf2\xf4\xfe",
+		invalid: "\x81\x83\x88\x90\x98",
+	},
+	{
+		name: "windows-1251",
+		// оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъёіїєґ
+		frequent: "\xee\xe5\xe0\xe8\xed\xf2\xf1\xf0\xe2\xeb\xea\xec\xe4\xef\xf3\xff\xfb\xe7\xfc\xe1\xe3\xf7\xe9\xf5\xe6\xf8\xfe\xf6\xf9\xfd\xf4\xfa\xb8\xb3\xbf\xba\xb4",
+		// ЂЃЉЊЌЋЏЎЈҐЁЄЇІЅАБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ
+		upper: "\x80\x81\x8a\x8c\x8d\x8e\x8f\xa1\xa3\xa5\xa8\xaa\xaf\xb2\xbd\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd2\xd3\xd4\xd5\xd6\xd7\xd8\xd9\xda\xdb\xdc\xdd\xde\xdf",
+		// ѓђљњќћџўµјѕ
+		letters: "\x83\x90\x9a\x9c\x9d\x9e\x9f\xa2\xb5\xbc\xbe",
+		invalid: "\x98",
+	},
+	{
+		name: "koi8-r",
+		// оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъё
+		frequent: "\xcf\xc5\xc1\xc9\xce\xd4\xd3\xd2\xd7\xcc\xcb\xcd\xc4\xd0\xd5\xd1\xd9\xda\xd8\xc2\xc7\xde\xca\xc8\xd6\xdb\xc0\xc3\xdd\xdc\xc6\xdf\xa3",
+		// ЁЮАБЦДЕФГХИЙКЛМНОПЯРСТУЖВЬЫЗШЭЩЧЪ
+		upper:   "\xb3\xe0\xe1\xe2\xe3\xe4\xe5\xe6\xe7\xe8\xe9\xea\xeb\xec\xed\xee\xef\xf0\xf1\xf2\xf3\xf4\xf5\xf6\xf7\xf8\xf9\xfa\xfb\xfc\xfd\xfe\xff",
+		letters: "",
+		invalid: "",
+	},
+	{
+		name: "koi8-u",
+		// оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъёіїєґ
+		frequent: "\xcf\xc5\xc1\xc9\xce\xd4\xd3\xd2\xd7\xcc\xcb\xcd\xc4\xd0\xd5\xd1\xd9\xda\xd8\xc2\xc7\xde\xca\xc8\xd6\xdb\xc0\xc3\xdd\xdc\xc6\xdf\xa3\xa6\xa7\xa4\xad",
+		// ЁЄІЇҐЮАБЦДЕФГХИЙКЛМНОПЯРСТУЖВЬЫЗШЭЩЧЪ
+		upper:   "\xb3\xb4\xb6\xb7\xbd\xe0\xe1\xe2\xe3\xe4\xe5\xe6\xe7\xe8\xe9\xea\xeb\xec\xed\xee\xef\xf0\xf1\xf2\xf3\xf4\xf5\xf6\xf7\xf8\xf9\xfa\xfb\xfc\xfd\xfe\xff",
+		letters: "",
+		invalid: "",
+	},
+	{
+		name: "ibm866",
+		// оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъё
+		frequent: "\xae\xa5\xa0\xa8\xad\xe2\xe1\xe0\xa2\xab\xaa\xac\xa4\xaf\xe3\xef\xeb\xa7\xec\xa1\xa3\xe7\xa9\xe5\xa6\xe8\xee\xe6\xe9\xed\xe4\xea\xf1",
+		// АБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯЁЄЇЎ
+		upper: "\x80\x81\x82\x83\x84\x85\x86\x87\x88\x89\x8a\x8b\x8c\x8d\x8e\x8f\x90\x91\x92\x93\x94\x95\x96\x97\x98\x99\x9a\x9b\x9c\x9d\x9e\x9f\xf0\xf2\xf4\xf6",
+		// єїў
+		letters: "\xf3\xf5\xf7",
+		invalid: "",
+	},
+	{
+		name: "iso-8859-5",
+		// оеаинтсрвлкмдпуяызьбгчйхжшюцщэфъёіїє
+		frequent: "\xde\xd5\xd0\xd8\xdd\xe2\xe1\xe0\xd2\xdb\xda\xdc\xd4\xdf\xe3\xef\xeb\xd7\xec\xd1\xd3\xe7\xd9\xe5\xd6\xe8\xee\xe6\xe9\xed\xe4\xea\xf1\xf6\xf7\xf4",
+		// ЁЂЃЄЅІЇЈЉЊЋЌЎЏАБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ
+		upper: "\xa1\xa2\xa3\xa4\xa5\xa6\xa7\xa8\xa9\xaa\xab\xac\xae\xaf\xb0\xb1\xb2\xb3\xb4\xb5\xb6\xb7\xb8\xb9\xba\xbb\xbc\xbd\xbe\xbf\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf",
+		// ђѓѕјљњћќўџ
+		letters: "\xf2\xf3\xf5\xf8\xf9\xfa\xfb\xfc\xfe\xff",
+		invalid: "\x80\x81\x82\x83\x84\x85\x86\x87\x88\x89\x8a\x8b\x8c\x8d\x8e\x8f\x90\x91\x92\x93\x94\x95\x96\x97\x98\x99\x9a\x9b\x9c\x9d\x9e\x9f",
+	},
+	{
+		name: "windows-1253",
+		// αοιετνσςρπκμυλωηάέίόύήώδγχθφβζξψ
+		frequent: "\xe1\xef\xe9\xe5\xf4\xed\xf3\xf2\xf1\xf0\xea\xec\xf5\xeb\xf9\xe7\xdc\xdd\xdf\xfc\xfd\xde\xfe\xe4\xe3\xf7\xe8\xf6\xe2\xe6\xee\xf8",
+		// ΆΈΉΊΌΎΏΑΒΓΔΕΖΗΘΙΚΛΜΝΞΟΠΡΣΤΥΦΧΨΩΪΫ
+		upper: "\xa2\xb8\xb9\xba\xbc\xbe\xbf\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd3\xd4\xd5\xd6\xd7\xd8\xd9\xda\xdb",
+		// ƒµΐΰϊϋ
+		letters: "\x83\xb5\xc0\xe0\xfa\xfb",
+		invalid: "\x81\x88\x8a\x8c\x8d\x8e\x8f\x90\x98\x9a\x9c\x9d\x9e\x9f\xaa\xd2\xff",
+	},
+	{
+		name: "windows-1254",
+		// ıüşçğöâîû
+		frequent: "\xfd\xfc\xfe\xe7\xf0\xf6\xe2\xee\xfb",
+		// ŠŒŸÀÁÂÃÄÅÆÇÈÉÊËÌÍÎÏĞÑÒÓÔÕÖØÙÚÛÜİŞ
+		upper: "\x8a\x8c\x9f\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd2\xd3\xd4\xd5\xd6\xd8\xd9\xda\xdb\xdc\xdd\xde",
+		// ƒˆšœªµºßàáãäåæèéêëìíïñòóôõøùúÿ
+		letters: "\x83\x88\x9a\x9c\xaa\xb5\xba\xdf\xe0\xe1\xe3\xe4\xe5\xe6\xe8\xe9\xea\xeb\xec\xed\xef\xf1\xf2\xf3\xf4\xf5\xf8\xf9\xfa\xff",
+		invalid: "\x81\x8d\x8e\x8f\x90\x9d\x9e",
+	},
+	{
+		name: "windows-1255",
+		// יוהלארבתמנשעדכקפחצסגזטףךםןץ
+		frequent: "\xe9\xe5\xe4\xec\xe0\xf8\xe1\xfa\xee\xf0\xf9\xf2\xe3\xeb\xf7\xf4\xe7\xf6\xf1\xe2\xe6\xe8\xf3\xea\xed\xef\xf5",
+		upper:    "", //
+		// ƒˆµװױײ
+		letters: "\x83\x88\xb5\xd4\xd5\xd6",
+		invalid: "\x81\x8a\x8c\x8d\x8e\x8f\x90\x9a\x9c\x9d\x9e\x9f\xca\xd9\xda\xdb\xdc\xdd\xde\xdf\xfb\xfc\xff",
+	},
+	{
+		name: "windows-1256",
+		// اليمنوهرتبدعسكفقحجشصطخذزضثغظءأإآةىئؤ
+		frequent: "\xc7\xe1\xed\xe3\xe4\xe6\xe5\xd1\xca\xc8\xcf\xda\xd3\xdf\xdd\xde\xcd\xcc\xd4\xd5\xd8\xce\xd0\xd2\xd6\xcb\xdb\xd9\xc1\xc3\xc5\xc2\xc9\xec\xc6\xc4",
+		// Œ
+		upper: "\x8c",
+		// پƒˆٹچژڈگکڑœںھµہـàâçèéêëîïôùûüے
+		letters: "\x81\x83\x88\x8a\x8d\x8e\x8f\x90\x98\x9a\x9c\x9f\xaa\xb5\xc0\xdc\xe0\xe2\xe7\xe8\xe9\xea\xeb\xee\xef\xf4\xf9\xfb\xfc\xff",
+		invalid: "",
+	},
+	{
+		name: "windows-1257",
+		// ąčęėįšųūžāēīķļņģ
+		frequent: "\xe0\xe8\xe6\xeb\xe1\xf0\xf8\xfb\xfe\xe2\xe7\xee\xed\xef\xf2\xec",
+		// ØŖÆĄĮĀĆÄÅĘĒČÉŹĖĢĶĪĻŠŃŅÓŌÕÖŲŁŚŪÜŻŽ
+		upper: "\xa8\xaa\xaf\xc0\xc1\xc2\xc3\xc4\xc5\xc6\xc7\xc8\xc9\xca\xcb\xcc\xcd\xce\xcf\xd0\xd1\xd2\xd3\xd4\xd5\xd6\xd8\xd9\xda\xdb\xdc\xdd\xde",
+		// ˇµøŗæßćäåéźńóōõöłśüż
+		letters: "\x8e\xb5\xb8\xba\xbf\xdf\xe3\xe4\xe5\xe9\xea\xf1\xf3\xf4\xf5\xf6\xf9\xfa\xfc\xfd",
+		invalid: "\x81\x83\x88\x8a\x8c\x90\x98\x9a\x9c\x9f\xa1\xa5",
+	},
+}
+
+// cjkFrequent holds the byte sequences of each multi-byte encoding's most
+// frequent characters
+var cjkFrequent = map[string]string{
+	// のにはをたがでてとしいるなかこすまりられっさあうもく
+	"shift_jis": "\x82\xcc\x82\xc9\x82\xcd\x82\xf0\x82\xbd\x82\xaa\x82\xc5\x82\xc4\x82\xc6\x82\xb5\x82\xa2\x82\xe9\x82\xc8\x82\xa9\x82\xb1\x82\xb7\x82\xdc\x82\xe8\x82\xe7\x82\xea\x82\xc1\x82\xb3\x82\xa0\x82\xa4\x82\xe0\x82\xad",
+	// のにはをたがでてとしいるなかこすまりられっさあうもく
+	"euc-jp": "\xa4\xce\xa4\xcb\xa4\xcf\xa4\xf2\xa4\xbf\xa4\xac\xa4\xc7\xa4\xc6\xa4\xc8\xa4\xb7\xa4\xa4\xa4\xeb\xa4\xca\xa4\xab\xa4\xb3\xa4\xb9\xa4\xde\xa4\xea\xa4\xe9\xa4\xec\xa4\xc3\xa4\xb5\xa4\xa2\xa4\xa6\xa4\xe2\xa4\xaf",
+	// 的一是在不了有和人这中大为上个国我以要他时来用们生到作地于出就分对成会可主发年动同工也能下过子说产种面而方后多定行学法所民得经
+	"gbk": "\xb5\xc4\xd2\xbb\xca\xc7\xd4\xda\xb2\xbb\xc1\xcb\xd3\xd0\xba\xcd\xc8\xcb\xd5\xe2\xd6\xd0\xb4\xf3\xce\xaa\xc9\xcf\xb8\xf6\xb9\xfa\xce\xd2\xd2\xd4\xd2\xaa\xcb\xfb\xca\xb1\xc0\xb4\xd3\xc3\xc3\xc7\xc9\xfa\xb5\xbd\xd7\xf7\xb5\xd8\xd3\xda\xb3\xf6\xbe\xcd\xb7\xd6\xb6\xd4\xb3\xc9\xbb\xe1\xbf\xc9\xd6\xf7\xb7\xa2\xc4\xea\xb6\xaf\xcd\xac\xb9\xa4\xd2\xb2\xc4\xdc\xcf\xc2\xb9\xfd\xd7\xd3\xcb\xb5\xb2\xfa\xd6\xd6\xc3\xe6\xb6\xf8\xb7\xbd\xba\xf3\xb6\xe0\xb6\xa8\xd0\xd0\xd1\xa7\xb7\xa8\xcb\xf9\xc3\xf1\xb5\xc3\xbe\xad",
+	// 的一是不了在人有我他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於著下自之年過發後作裡
+	"big5": "\xaa\xba\xa4\x40\xac\x4f\xa4\xa3\xa4\x46\xa6\x62\xa4\x48\xa6\xb3\xa7\xda\xa5\x4c\xb3\x6f\xad\xd3\xad\xcc\xa4\xa4\xa8\xd3\xa4\x57\xa4\x6a\xac\xb0\xa9\x4d\xb0\xea\xa6\x61\xa8\xec\xa5\x48\xbb\xa1\xae\xc9\xad\x6e\xb4\x4e\xa5\x58\xb7\x7c\xa5\x69\xa4\x5d\xa7\x41\xb9\xef\xa5\xcd\xaf\xe0\xa6\xd3\xa4\x6c\xa8\xba\xb1\x6f\xa9\xf3\xb5\xdb\xa4\x55\xa6\xdb\xa4\xa7\xa6\x7e\xb9\x4c\xb5\x6f\xab\xe1\xa7\x40\xb8\xcc",
+	// 이다의는에을하고가지한서로기사정리도수시자대그일어적나보인있것들아해상게국전요와
+	"euc-kr": "\xc0\xcc\xb4\xd9\xc0\xc7\xb4\xc2\xbf\xa1\xc0\xbb\xc7\xcf\xb0\xed\xb0\xa1\xc1\xf6\xc7\xd1\xbc\xad\xb7\xce\xb1\xe2\xbb\xe7\xc1\xa4\xb8\xae\xb5\xb5\xbc\xf6\xbd\xc3\xc0\xda\xb4\xeb\xb1\xd7\xc0\xcf\xbe\xee\xc0\xfb\xb3\xaa\xba\xb8\xc0\xce\xc0\xd6\xb0\xcd\xb5\xe9\xbe\xc6\xc7\xd8\xbb\xf3\xb0\xd4\xb1\xb9\xc0\xfc\xbf\xe4\xbf\xcd",
+}
+
+// cjkModel describes a legacy multi-byte encoding
+type cjkModel struct {
+	name string
+	// char measures the non-ASCII character at the start of s, returning
+	// its length and weight. n is 0 for an invalid byte and -1 if s ends
+	// before the character does.
+	char func(s []byte) (n int, weight float64)
+}
+
+// cjkModels are the multi-byte candidates of DetectEncoding
+var cjkModels = []cjkModel{
+	{"shift_jis", shiftJISChar},
+	{"euc-jp", eucJPChar},
+	{"gbk", gbkChar},
+	{"big5", big5Char},
+	{"euc-kr", eucKRChar},
+}
+
+// inRange reports whether lo <= b <= hi
+func inRange(b byte, lo byte, hi byte) bool {
+	return b >= lo && b <= hi
+}
+
+// shiftJISChar measures a Shift_JIS character; kana score above kanji
+func shiftJISChar(s []byte) (int, float64) {
+	lead := s[0]
+	if inRange(lead, 0xA1, 0xDF) {
+		// Half-width katakana
+		return 1, 0.1
+	}
+	if !inRange(lead, 0x81, 0x9F) && !inRange(lead, 0xE0, 0xFC) {
+		return 0, 0
+	}
+	if len(s) < 2 {
+		return -1, 0
+	}
+	trail := s[1]
+	if !inRange(trail, 0x40, 0x7E) && !inRange(trail, 0x80, 0xFC) {
+		return 0, 0
+	}
+	switch {
+	case lead == 0x82 || (lead == 0x83 && trail <= 0x96):
+		return 2, 0.6
+	case lead == 0x81:
+		return 2, 0.2
+	case inRange(lead, 0x88, 0x9F) || inRange(lead, 0xE0, 0xEA):
+		return 2, 0.3
+	}
+	return 2, 0.05
+}
+
+// eucJPChar measures an EUC-JP character; kana score above kanji
+func eucJPChar(s []byte) (int, float64) {
+	lead := s[0]
+	n := 2
+	switch {
+	case lead == 0x8E:
+		if len(s) < 2 {
+			return -1, 0
+		}
+		if !inRange(s[1], 0xA1, 0xDF) {
+			return 0, 0
+		}
+		return 2, 0.1
+	case lead == 0x8F:
+		s, n = s[1:], 3
+	case !inRange(lead, 0xA1, 0xFE):
+		return 0, 0
+	}
+	if len(s) < 2 {
+		return -1, 0
+	}
+	if !inRange(s[0], 0xA1, 0xFE) || !inRange(s[1], 0xA1, 0xFE) {
+		return 0, 0
+	}
+	switch {
+	case n == 3:
+		return n, 0.05
+	case lead == 0xA4 || lead == 0xA5:
+		return n, 0.6
+	case inRange(lead, 0xB0, 0xF4):
+		return n, 0.3
+	case inRange(lead, 0xA1, 0xA3):
+		return n, 0.2
+	}
+	return n, 0.05
+}
+
+// gbkChar measures a GBK or GB18030 character; GB2312 hanzi score highest
+func gbkChar(s []byte) (int, float64) {
+	lead := s[0]
+	if lead == 0x80 {
+		// The euro sign
+		return 1, 0.05
+	}
+	if lead == 0xFF {
+		return 0, 0
+	}
+	if len(s) < 2 {
+		return -1, 0
+	}
+	trail := s[1]
+	if inRange(trail, 0x30, 0x39) {
+		// GB18030 four-byte sequence
+		if len(s) < 4 {
+			return -1, 0
+		}
+		if !inRange(s[2], 0x81, 0xFE) || !inRange(s[3], 0x30, 0x39) {
+			return 0, 0
+		}
+		return 4, 0.05
+	}
+	if !inRange(trail, 0x40, 0x7E) && !inRange(trail, 0x80, 0xFE) {
+		return 0, 0
+	}
+	switch {
+	case trail >= 0xA1 && inRange(lead, 0xB0, 0xD7):
+		return 2, 0.4
+	case trail >= 0xA1 && inRange(lead, 0xD8, 0xF7):
+		return 2, 0.2
+	}
+	return 2, 0.1
+}
+
+// big5Char measures a Big5 character; frequently used hanzi score highest
+func big5Char(s []byte) (int, float64) {
+	lead := s[0]
+	if !inRange(lead, 0x81, 0xFE) {
+		return 0, 0
+	}
+	if len(s) < 2 {
+		return -1, 0
+	}
+	trail := s[1]
+	if !inRange(trail, 0x40, 0x7E) && !inRange(trail, 0xA1, 0xFE) {
+		return 0, 0
+	}
+	code := uint16(lead)<<8 | uint16(trail)
+	switch {
+	case code >= 0xA440 && code <= 0xC67E:
+		return 2, 0.4
+	case code >= 0xC940 && code <= 0xF9D5:
+		return 2, 0.2
+	case inRange(lead, 0xA1, 0xA3):
+		return 2, 0.2
+	}
+	return 2, 0.05
+}
+
+// eucKRChar measures an EUC-KR (windows-949) character; KS X 1001 hangul
+// score highest
+func eucKRChar(s []byte) (int, float64) {
+	lead := s[0]
+	if !inRange(lead, 0x81, 0xFE) {
+		return 0, 0
+	}
+	if len(s) < 2 {
+		return -1, 0
+	}
+	trail := s[1]
+	if !inRange(trail, 0x41, 0x5A) && !inRange(trail, 0x61, 0x7A) && !inRange(trail, 0x81, 0xFE) {
+		return 0, 0
+	}
+	switch {
+	case trail < 0xA1:
+		// Unified Hangul Code extension
+		return 2, 0.2
+	case inRange(lead, 0xB0, 0xC8):
+		return 2, 0.5
+	case inRange(lead, 0xCA, 0xFD):
+		return 2, 0.2
+	}
+	return 2, 0.1
+}
+
+// singleByteWeights are the per-byte scores derived from a singleByteModel
+type singleByteWeights struct {
+	weight [128]float64
+	upper  [128]bool
+	lower  [128]bool
+}
+
+// newSingleByteWeights scores the letters of m. Frequent letters score by
+// rank in steps of ten, other letters a little, and invalid bytes count
+// against the model.
+func newSingleByteWeights(m *singleByteModel) *singleByteWeights {
+	w := &singleByteWeights{}
+	for i := 0; i < len(m.frequent); i++ {
+		b := m.frequent[i] - 0x80
+		w.weight[b] = 1.0 - 0.3*float64(min(i/10, 2))
+		w.lower[b] = true
+	}
+	for i := 0; i < len(m.upper); i++ {
+		b := m.upper[i] - 0x80
+		w.weight[b] = 0.3
+		w.upper[b] = true
+	}
+	for i := 0; i < len(m.letters); i++ {
+		b := m.letters[i] - 0x80
+		w.weight[b] = 0.3
+		w.lower[b] = true
+	}
+	for i := 0; i < len(m.invalid); i++ {
+		w.weight[m.invalid[i]-0x80] = -1
+	}
+	return w
+}
+
+// singleByteModelWeights holds the weights of singleByteModels by index
+var singleByteModelWeights = func() []*singleByteWeights {
+	weights := make([]*singleByteWeights, len(singleByteModels))
+	for i := range singleByteModels {
+		weights[i] = newSingleByteWeights(&singleByteModels[i])
+	}
+	return weights
+}()
+
+// score scores sample as text in the weights' encoding. An upper case
+// letter straight after a lower case one is penalised, which separates
+// encodings that share a repertoire but place its cases differently.
+func (w *singleByteWeights) score(sample []byte) float64 {
+	score, units := 0.0, 0
+	prevLower := false
+	for _, b := range sample {
+		if b < 0x80 {
+			prevLower = b >= 'a' && b <= 'z'
+			continue
+		}
+		units++
+		i := b - 0x80
+		score += w.weight[i]
+		if w.upper[i] && prevLower {
+			score--
+		}
+		prevLower = w.lower[i]
+	}
+	return confidence(score, units)
+}
+
+// score scores sample as text in the model's encoding. Invalid bytes count
+// against the model and its most frequent characters score in full.
+func (m *cjkModel) score(sample []byte) float64 {
+	frequent := cjkFrequent[m.name]
+	score, units := 0.0, 0
+	for i := 0; i < len(sample); {
+		if sample[i] < 0x80 {
+			i++
+			continue
+		}
+		n, weight := m.char(sample[i:])
+		if n < 0 {
+			// Ignore a character cut off at the end of the sample
+			break
+		}
+		units++
+		if n == 0 {
+			score--
+			i++
+			continue
+		}
+		if n == 2 && isFrequentPair(frequent, sample[i], sample[i+1]) {
+			weight = 1
+		}
+		score += weight
+		i += n
+	}
+	return confidence(score, units)
+}
+
+// isFrequentPair reports whether the two bytes a, b appear as a pair in frequent
+func isFrequentPair(frequent string, a byte, b byte) bool {
+	for i := 0; i+1 < len(frequent); i += 2 {
+		if frequent[i] == a && frequent[i+1] == b {
+			return true
+		}
+	}
+	return false
+}
+
+// confidence turns a total score over units into a confidence between 0 and 1
+func confidence(score float64, units int) float64 {
+	if units == 0 || score <= 0 {
+		return 0
+	}
+	return min(score/float64(units), 1)
+}
+
+// isISO2022JP reports whether an all-ASCII sample switches to JIS X 0208
+func isISO2022JP(sample []byte) bool {
+	for _, escape := range []string{"\x1b$B", "\x1b$@"} {
+		for i := 0; i+len(escape) <= len(sample); i++ {
+			if string(sample[i:i+len(escape)]) == escape {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isUTF8Sample reports whether sample is UTF-8. A character cut off at the
+// end, as when the sample is the start of a longer input, is allowed only
+// after complete multi-byte characters: a lone trailing lead byte, as in
+// windows-1252 "caf\xe9", is no evidence of UTF-8.
+func isUTF8Sample(sample []byte) bool {
+	if utf8.Valid(sample) {
+		return true
+	}
+	complete := sample[:len(sample)-incompleteSuffixLen(string(sample))]
+	return len(complete) < len(sample) && asciiPrefixLen(complete) < len(complete) && utf8.Valid(complete)
+}
+
+// DetectEncoding guesses the encoding of sample when there is no BOM,
+// transport layer charset or declaration to go by. It scores UTF-8, the
+// legacy CJK encodings, the Cyrillic encodings and windows-1250 to
+// windows-1257 with byte-frequency models of their languages, and returns
+// the candidates with any support, most likely first. The result is
+// deterministic and computed offline. An all-ASCII sample decodes the same
+// in every ASCII-compatible encoding, so only ISO-2022-JP is ever guessed
+// for one.
+func DetectEncoding(sample []byte) []Guess {
+	if asciiPrefixLen(sample) == len(sample) {
+		if isISO2022JP(sample) {
+			return []Guess{{Encoding: Lookup("iso-2022-jp"), Confidence: 1}}
+		}
+		return nil
+	}
+
+	var guesses []Guess
+	if isUTF8Sample(sample) {
+		guesses = append(guesses, Guess{Encoding: UTF8, Confidence: 1})
+	}
+	for i := range cjkModels {
+		if c := cjkModels[i].score(sample); c > 0 {
+			guesses = append(guesses, Guess{Encoding: Lookup(cjkModels[i].name), Confidence: c})
+		}
+	}
+	for i, w := range singleByteModelWeights {
+		if c := w.score(sample); c > 0 {
+			guesses = append(guesses, Guess{Encoding: Lookup(singleByteModels[i].name), Confidence: c})
+		}
+	}
+
+	sort.SliceStable(guesses, func(i, j int) bool {
+		return guesses[i].Confidence > guesses[j].Confidence
+	})
+	return guesses
+}
+
+// DecodeWithDetection decodes input with the encoding of its BOM, then the
+// best guess of DetectEncoding, then the fallback encoding. A guess is
+// reported as SourceDetector and is only tentative. A guess that this
+// package has no codec for, such as windows-1251, fails with
+// ErrUnsupportedEncoding, though the result is still returned.
+func DecodeWithDetection(input []byte, fallbackEncoding interface{}, errors string) (string, *DecodeResult, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	// Fail early if encoding is invalid
+	fallbackEnc, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, remaining := sniffBOM(input)
+	if result == nil {
+		if guesses := DetectEncoding(input); len(guesses) > 0 {
+			result = newDecodeResult(guesses[0].Encoding, SourceDetector)
+		}
+	}
+	if result == nil {
+		result = newDecodeResult(fallbackEnc, SourceFallback)
+	}
+
+	return decodeWithResult(remaining, result, errors)
+}
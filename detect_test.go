@@ -0,0 +1,128 @@
+package webencodings
+
+import "testing"
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		language string
+		expected string
+		sample   string
+	}{
+		{"russian", "windows-1251", "\xd1\xfa\xe5\xf8\xfc \xe6\xe5 \xe5\xf9\xb8 \xfd\xf2\xe8\xf5 \xec\xff\xe3\xea\xe8\xf5 \xf4\xf0\xe0\xed\xf6\xf3\xe7\xf1\xea\xe8\xf5 \xe1\xf3\xeb\xee\xea, \xe4\xe0 \xe2\xfb\xef\xe5\xe9 \xf7\xe0\xfe. \xcc\xee\xf1\xea\xe2\xe0 \xff\xe2\xeb\xff\xe5\xf2\xf1\xff \xf1\xf2\xee\xeb\xe8\xf6\xe5\xe9 \xd0\xee\xf1\xf1\xe8\xe8, \xe0 \xd1\xe0\xed\xea\xf2-\xcf\xe5\xf2\xe5\xf0\xe1\xf3\xf0\xe3 \xe1\xfb\xeb \xe5\xb8 \xf1\xf2\xee\xeb\xe8\xf6\xe5\xe9 \xf0\xe0\xed\xfc\xf8\xe5."},
+		{"russian", "koi8-r", "\xf3\xdf\xc5\xdb\xd8 \xd6\xc5 \xc5\xdd\xa3 \xdc\xd4\xc9\xc8 \xcd\xd1\xc7\xcb\xc9\xc8 \xc6\xd2\xc1\xce\xc3\xd5\xda\xd3\xcb\xc9\xc8 \xc2\xd5\xcc\xcf\xcb, \xc4\xc1 \xd7\xd9\xd0\xc5\xca \xde\xc1\xc0. \xed\xcf\xd3\xcb\xd7\xc1 \xd1\xd7\xcc\xd1\xc5\xd4\xd3\xd1 \xd3\xd4\xcf\xcc\xc9\xc3\xc5\xca \xf2\xcf\xd3\xd3\xc9\xc9, \xc1 \xf3\xc1\xce\xcb\xd4-\xf0\xc5\xd4\xc5\xd2\xc2\xd5\xd2\xc7 \xc2\xd9\xcc \xc5\xa3 \xd3\xd4\xcf\xcc\xc9\xc3\xc5\xca \xd2\xc1\xce\xd8\xdb\xc5."},
+		{"russian", "ibm866", "\x91\xea\xa5\xe8\xec \xa6\xa5 \xa5\xe9\xf1 \xed\xe2\xa8\xe5 \xac\xef\xa3\xaa\xa8\xe5 \xe4\xe0\xa0\xad\xe6\xe3\xa7\xe1\xaa\xa8\xe5 \xa1\xe3\xab\xae\xaa, \xa4\xa0 \xa2\xeb\xaf\xa5\xa9 \xe7\xa0\xee. \x8c\xae\xe1\xaa\xa2\xa0 \xef\xa2\xab\xef\xa5\xe2\xe1\xef \xe1\xe2\xae\xab\xa8\xe6\xa5\xa9 \x90\xae\xe1\xe1\xa8\xa8, \xa0 \x91\xa0\xad\xaa\xe2-\x8f\xa5\xe2\xa5\xe0\xa1\xe3\xe0\xa3 \xa1\xeb\xab \xa5\xf1 \xe1\xe2\xae\xab\xa8\xe6\xa5\xa9 \xe0\xa0\xad\xec\xe8\xa5."},
+		{"russian", "iso-8859-5", "\xc1\xea\xd5\xe8\xec \xd6\xd5 \xd5\xe9\xf1 \xed\xe2\xd8\xe5 \xdc\xef\xd3\xda\xd8\xe5 \xe4\xe0\xd0\xdd\xe6\xe3\xd7\xe1\xda\xd8\xe5 \xd1\xe3\xdb\xde\xda, \xd4\xd0 \xd2\xeb\xdf\xd5\xd9 \xe7\xd0\xee. \xbc\xde\xe1\xda\xd2\xd0 \xef\xd2\xdb\xef\xd5\xe2\xe1\xef \xe1\xe2\xde\xdb\xd8\xe6\xd5\xd9 \xc0\xde\xe1\xe1\xd8\xd8, \xd0 \xc1\xd0\xdd\xda\xe2-\xbf\xd5\xe2\xd5\xe0\xd1\xe3\xe0\xd3 \xd1\xeb\xdb \xd5\xf1 \xe1\xe2\xde\xdb\xd8\xe6\xd5\xd9 \xe0\xd0\xdd\xec\xe8\xd5."},
+		{"japanese", "shift_jis", "\x8c\xe1\x94y\x82\xcd\x94L\x82\xc5\x82\xa0\x82\xe9\x81B\x96\xbc\x91O\x82\xcd\x82\xdc\x82\xbe\x96\xb3\x82\xa2\x81B\x82\xc7\x82\xb1\x82\xc5\x90\xb6\x82\xea\x82\xbd\x82\xa9\x82\xc6\x82\xf1\x82\xc6\x8c\xa9\x93\x96\x82\xaa\x82\xc2\x82\xa9\x82\xca\x81B\x89\xbd\x82\xc5\x82\xe0\x94\x96\x88\xc3\x82\xa2\x82\xb6\x82\xdf\x82\xb6\x82\xdf\x82\xb5\x82\xbd\x8f\x8a\x82\xc5\x83j\x83\x83\x81[\x83j\x83\x83\x81[\x8b\x83\x82\xa2\x82\xc4\x82\xa2\x82\xbd\x8e\x96\x82\xbe\x82\xaf\x82\xcd\x8bL\x89\xaf\x82\xb5\x82\xc4\x82\xa2\x82\xe9\x81B"},
+		{"japanese", "euc-jp", "\xb8\xe3\xc7\xda\xa4\xcf\xc7\xad\xa4\xc7\xa4\xa2\xa4\xeb\xa1\xa3\xcc\xbe\xc1\xb0\xa4\xcf\xa4\xde\xa4\xc0\xcc\xb5\xa4\xa4\xa1\xa3\xa4\xc9\xa4\xb3\xa4\xc7\xc0\xb8\xa4\xec\xa4\xbf\xa4\xab\xa4\xc8\xa4\xf3\xa4\xc8\xb8\xab\xc5\xf6\xa4\xac\xa4\xc4\xa4\xab\xa4\xcc\xa1\xa3\xb2\xbf\xa4\xc7\xa4\xe2\xc7\xf6\xb0\xc5\xa4\xa4\xa4\xb8\xa4\xe1\xa4\xb8\xa4\xe1\xa4\xb7\xa4\xbf\xbd\xea\xa4\xc7\xa5\xcb\xa5\xe3\xa1\xbc\xa5\xcb\xa5\xe3\xa1\xbc\xb5\xe3\xa4\xa4\xa4\xc6\xa4\xa4\xa4\xbf\xbb\xf6\xa4\xc0\xa4\xb1\xa4\xcf\xb5\xad\xb2\xb1\xa4\xb7\xa4\xc6\xa4\xa4\xa4\xeb\xa1\xa3"},
+		{"chinese", "gbk", "\xd6\xd0\xbb\xaa\xc8\xcb\xc3\xf1\xb9\xb2\xba\xcd\xb9\xfa\xca\xc7\xb9\xa4\xc8\xcb\xbd\xd7\xbc\xb6\xc1\xec\xb5\xbc\xb5\xc4\xa1\xa2\xd2\xd4\xb9\xa4\xc5\xa9\xc1\xaa\xc3\xcb\xce\xaa\xbb\xf9\xb4\xa1\xb5\xc4\xc8\xcb\xc3\xf1\xc3\xf1\xd6\xf7\xd7\xa8\xd5\xfe\xb5\xc4\xc9\xe7\xbb\xe1\xd6\xf7\xd2\xe5\xb9\xfa\xbc\xd2\xa1\xa3\xc9\xe7\xbb\xe1\xd6\xf7\xd2\xe5\xd6\xc6\xb6\xc8\xca\xc7\xd6\xd0\xbb\xaa\xc8\xcb\xc3\xf1\xb9\xb2\xba\xcd\xb9\xfa\xb5\xc4\xb8\xf9\xb1\xbe\xd6\xc6\xb6\xc8\xa1\xa3"},
+		{"traditional", "big5", "\xa7\xda\xad\xcc\xa6b\xb3o\xad\xd3\xae\xc9\xad\xd4\xa4\xa3\xaf\xe0\xbb\xa1\xa5X\xa5L\xaa\xba\xa6W\xa6r\xa1A\xa6]\xac\xb0\xa4j\xaea\xb3\xa3\xaa\xbe\xb9D\xb3o\xacO\xa4@\xad\xd3\xaf\xb5\xb1K\xa1C\xa5L\xbb\xa1\xa6\xdb\xa4v\xb7|\xa6b\xa6~\xa9\xb3\xa4\xa7\xabe\xa6^\xa8\xd3\xa1A\xa5i\xacO\xa7\xda\xad\xcc\xb3\xa3\xa4\xa3\xac\xdb\xabH\xa1C"},
+		{"korean", "euc-kr", "\xb4\xeb\xc7\xd1\xb9\xce\xb1\xb9\xc0\xba \xb9\xce\xc1\xd6\xb0\xf8\xc8\xad\xb1\xb9\xc0\xcc\xb4\xd9. \xb4\xeb\xc7\xd1\xb9\xce\xb1\xb9\xc0\xc7 \xc1\xd6\xb1\xc7\xc0\xba \xb1\xb9\xb9\xce\xbf\xa1\xb0\xd4 \xc0\xd6\xb0\xed, \xb8\xf0\xb5\xe7 \xb1\xc7\xb7\xc2\xc0\xba \xb1\xb9\xb9\xce\xc0\xb8\xb7\xce\xba\xce\xc5\xcd \xb3\xaa\xbf\xc2\xb4\xd9. \xb4\xeb\xc7\xd1\xb9\xce\xb1\xb9\xc0\xc7 \xbf\xb5\xc5\xe4\xb4\xc2 \xc7\xd1\xb9\xdd\xb5\xb5\xbf\xcd \xb1\xd7 \xba\xce\xbc\xd3\xb5\xb5\xbc\xad\xb7\xce \xc7\xd1\xb4\xd9."},
+		{"french", "windows-1252", "Le c\x9cur a ses raisons que la raison ne conna\xeet point. Il \xe9tait d\xe9j\xe0 tr\xe8s tard quand nous sommes arriv\xe9s \xe0 la gare, o\xf9 l'on c\xe9l\xe9brait la f\xeate."},
+		{"polish", "windows-1250", "Za\xbf\xf3\xb3\xe6 g\xea\x9cl\xb9 ja\x9f\xf1. Pchn\xb9\xe6 w t\xea \xb3\xf3d\x9f je\xbfa lub o\x9cm skrzy\xf1 fig. Wi\xeakszo\x9c\xe6 mieszka\xf1c\xf3w \xbfyje w miastach."},
+		{"greek", "windows-1253", "\xce\xe5\xf3\xea\xe5\xf0\xdc\xe6\xf9 \xf4\xe7\xed \xf8\xf5\xf7\xef\xf6\xe8\xfc\xf1\xe1 \xe2\xe4\xe5\xeb\xf5\xe3\xec\xdf\xe1. \xc7 \xe5\xeb\xeb\xe7\xed\xe9\xea\xde \xe3\xeb\xfe\xf3\xf3\xe1 \xe5\xdf\xed\xe1\xe9 \xec\xdf\xe1 \xe1\xf0\xfc \xf4\xe9\xf2 \xe1\xf1\xf7\xe1\xe9\xfc\xf4\xe5\xf1\xe5\xf2 \xe3\xeb\xfe\xf3\xf3\xe5\xf2 \xf4\xef\xf5 \xea\xfc\xf3\xec\xef\xf5."},
+		{"turkish", "windows-1254", "Pijamal\xfd hasta ya\xf0\xfdz \xfeof\xf6re \xe7abucak g\xfcvendi. T\xfcrk\xe7e \xf6\xf0renmek i\xe7in her g\xfcn \xe7al\xfd\xfe\xfdyorum ve \xe7ok g\xfczel buluyorum."},
+		{"hebrew", "windows-1255", "\xf9\xec\xe5\xed \xf2\xe5\xec\xed, \xe6\xe4\xe5 \xe8\xf7\xf1\xe8 \xe1\xf2\xe1\xf8\xe9\xfa \xec\xe1\xe3\xe9\xf7\xe4 \xf9\xec \xe6\xe9\xe4\xe5\xe9 \xe4\xf7\xe9\xe3\xe5\xe3. \xe9\xf8\xe5\xf9\xec\xe9\xed \xe4\xe9\xe0 \xe1\xe9\xf8\xfa \xe9\xf9\xf8\xe0\xec."},
+		{"arabic", "windows-1256", "\xe3\xd1\xcd\xc8\xc7 \xc8\xc7\xe1\xda\xc7\xe1\xe3\xa1 \xe5\xd0\xc7 \xe4\xd5 \xc8\xc7\xe1\xe1\xdb\xc9 \xc7\xe1\xda\xd1\xc8\xed\xc9 \xe1\xc7\xce\xca\xc8\xc7\xd1 \xc7\xdf\xca\xd4\xc7\xdd \xc7\xe1\xca\xd1\xe3\xed\xd2. \xc7\xe1\xde\xc7\xe5\xd1\xc9 \xe5\xed \xda\xc7\xd5\xe3\xc9 \xe3\xd5\xd1."},
+		{"lithuanian", "windows-1257", "\xc1linkdama fechtuotojo \xf0paga sublyk\xe8iojusi pragr\xe6\xfe\xeb apval\xf8 arb\xfbz\xe0. Lietuvi\xf8 kalba yra viena seniausi\xf8 kalb\xf8."},
+		{"french", "utf-8", "Il était déjà très tard quand nous sommes arrivés à la gare."},
+		{"japanese", "iso-2022-jp", "\x1b$B$3$s$K$A$O\x1b(B"},
+	}
+
+	for _, test := range tests {
+		guesses := DetectEncoding([]byte(test.sample))
+		if len(guesses) == 0 {
+			t.Errorf("DetectEncoding(%s in %s) returned no guesses", test.language, test.expected)
+			continue
+		}
+		if guesses[0].Encoding.Name != test.expected {
+			t.Errorf("DetectEncoding(%s in %s): expected %s first, got %v", test.language, test.expected, test.expected, guesses)
+		}
+
+		for i, guess := range guesses {
+			if guess.Confidence <= 0 || guess.Confidence > 1 {
+				t.Errorf("DetectEncoding(%s in %s): confidence %v out of range", test.language, test.expected, guess.Confidence)
+			}
+			if i > 0 && guess.Confidence > guesses[i-1].Confidence {
+				t.Errorf("DetectEncoding(%s in %s): guesses not ranked: %v", test.language, test.expected, guesses)
+			}
+		}
+	}
+}
+
+func TestDetectEncodingASCII(t *testing.T) {
+	if guesses := DetectEncoding([]byte("plain ASCII text")); guesses != nil {
+		t.Errorf("Expected no guesses for ASCII, got %v", guesses)
+	}
+	if guesses := DetectEncoding(nil); guesses != nil {
+		t.Errorf("Expected no guesses for empty input, got %v", guesses)
+	}
+}
+
+func TestDetectEncodingDeterministic(t *testing.T) {
+	sample := []byte("\xcf\xd2\xc9\xd7\xc5\xd4 \xcd\xc9\xd2")
+	first := DetectEncoding(sample)
+	for i := 0; i < 10; i++ {
+		guesses := DetectEncoding(sample)
+		if len(guesses) != len(first) {
+			t.Fatalf("Expected %v, got %v", first, guesses)
+		}
+		for j := range guesses {
+			if guesses[j] != first[j] {
+				t.Fatalf("Expected %v, got %v", first, guesses)
+			}
+		}
+	}
+}
+
+func TestDetectEncodingTruncatedUTF8(t *testing.T) {
+	tests := []struct {
+		sample string
+		utf8   bool
+	}{
+		{"caf\xc3\xa9", true},
+		{"d\xc3\xa9j\xc3\xa0 \xe2\x82", true}, // cut off at a read boundary
+		{"caf\xe9", false},                    // a lone lead byte in windows-1252
+		{"na\xefve caf\xe9", false},
+		{"\xe2\x82", false},
+	}
+
+	for _, test := range tests {
+		guesses := DetectEncoding([]byte(test.sample))
+		isUTF8 := len(guesses) > 0 && guesses[0].Encoding == UTF8
+		if isUTF8 != test.utf8 {
+			t.Errorf("DetectEncoding(%q): expected utf-8 first to be %v, got %v", test.sample, test.utf8, guesses)
+		}
+	}
+}
+
+func TestDecodeWithDetection(t *testing.T) {
+	tests := []struct {
+		input    string
+		encoding string
+		source   Source
+		decoded  string
+		err      error
+	}{
+		{"\xEF\xBB\xBF\xd0\xbc\xd0\xb8\xd1\x80", "utf-8", SourceBOM, "\u043c\u0438\u0440", nil},
+		{"\xd0\xbc\xd0\xb8\xd1\x80", "utf-8", SourceDetector, "\u043c\u0438\u0440", nil},
+		{"\xcc\xee\xf1\xea\xe2\xe0 \xff\xe2\xeb\xff\xe5\xf2\xf1\xff \xf1\xf2\xee\xeb\xe8\xf6\xe5\xe9", "windows-1251", SourceDetector, "", ErrUnsupportedEncoding},
+		{"plain ascii", "x-user-defined", SourceFallback, "plain ascii", nil},
+	}
+
+	for _, test := range tests {
+		decoded, result, err := DecodeWithDetection([]byte(test.input), "x-user-defined", "")
+		if err != test.err {
+			t.Errorf("DecodeWithDetection(%q): expected error %v, got %v", test.input, test.err, err)
+		}
+		if result == nil || result.Encoding.Name != test.encoding || result.Source != test.source || result.Confidence != test.source.Confidence() {
+			t.Errorf("DecodeWithDetection(%q): expected %s from %s, got %+v", test.input, test.encoding, test.source, result)
+		}
+		if decoded != test.decoded {
+			t.Errorf("DecodeWithDetection(%q): expected %q, got %q", test.input, test.decoded, decoded)
+		}
+	}
+
+	if _, result, err := DecodeWithDetection([]byte("abc"), "unknown", ""); err != ErrUnknownEncoding || result != nil {
+		t.Errorf("DecodeWithDetection with unknown fallback: expected nil and ErrUnknownEncoding, got %v and %v", result, err)
+	}
+}
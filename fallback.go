@@ -0,0 +1,141 @@
+package webencodings
+
+import "strings"
+
+// localeFallbacks maps language subtags to the fallback encodings the HTML
+// standard suggests for them. Chinese depends on the script or region and
+// is handled separately; every other language falls back to windows-1252.
+var localeFallbacks = map[string]string{
+	"ar":  "windows-1256",
+	"ba":  "windows-1251",
+	"be":  "windows-1251",
+	"bg":  "windows-1251",
+	"cs":  "windows-1250",
+	"el":  "iso-8859-7",
+	"et":  "windows-1257",
+	"fa":  "windows-1256",
+	"he":  "windows-1255",
+	"hr":  "windows-1250",
+	"hu":  "iso-8859-2",
+	"ja":  "shift_jis",
+	"kk":  "windows-1251",
+	"ko":  "euc-kr",
+	"ku":  "windows-1254",
+	"ky":  "windows-1251",
+	"lt":  "windows-1257",
+	"lv":  "windows-1257",
+	"mk":  "windows-1251",
+	"pl":  "iso-8859-2",
+	"ru":  "windows-1251",
+	"sah": "windows-1251",
+	"sk":  "windows-1250",
+	"sl":  "iso-8859-2",
+	"sr":  "windows-1251",
+	"tg":  "windows-1251",
+	"th":  "windows-874",
+	"tr":  "windows-1254",
+	"tt":  "windows-1251",
+	"uk":  "windows-1251",
+	"vi":  "windows-1258",
+}
+
+// tldLocales maps country code top-level domains to the locale whose
+// fallback encoding legacy content under them most likely uses
+var tldLocales = map[string]string{
+	"ae": "ar", "bh": "ar", "dz": "ar", "eg": "ar", "iq": "ar", "jo": "ar",
+	"kw": "ar", "lb": "ar", "ly": "ar", "ma": "ar", "om": "ar", "qa": "ar",
+	"sa": "ar", "sd": "ar", "sy": "ar", "tn": "ar", "ye": "ar",
+	"bg": "bg",
+	"by": "be",
+	"cn": "zh-CN",
+	"cz": "cs",
+	"ee": "et",
+	"gr": "el",
+	"hk": "zh-HK",
+	"hr": "hr",
+	"hu": "hu",
+	"il": "he",
+	"ir": "fa",
+	"jp": "ja",
+	"kg": "ky",
+	"kr": "ko",
+	"kz": "kk",
+	"lt": "lt",
+	"lv": "lv",
+	"mk": "mk",
+	"mo": "zh-MO",
+	"pl": "pl",
+	"rs": "sr",
+	"ru": "ru",
+	"si": "sl",
+	"sk": "sk",
+	"su": "ru",
+	"th": "th",
+	"tj": "tg",
+	"tr": "tr",
+	"tw": "zh-TW",
+	"ua": "uk",
+	"vn": "vi",
+	// Internationalized ccTLDs, in both their Unicode and ASCII forms
+	"рф":           "ru",
+	"xn--p1ai":     "ru",
+	"бг":           "bg",
+	"xn--90ae":     "bg",
+	"бел":          "be",
+	"xn--90ais":    "be",
+	"укр":          "uk",
+	"xn--j1amh":    "uk",
+	"中国":           "zh-CN",
+	"xn--fiqs8s":   "zh-CN",
+	"台灣":           "zh-TW",
+	"xn--kpry57d":  "zh-TW",
+	"香港":           "zh-HK",
+	"xn--j6w193g":  "zh-HK",
+	"한국":           "ko",
+	"xn--3e0b707e": "ko",
+}
+
+// LocaleFallbackEncoding returns the fallback encoding the HTML standard
+// suggests for a BCP 47 language tag such as "ru", "ja-JP" or "zh-Hant",
+// for use as the fallbackEncoding of Decode. Unknown and empty tags get
+// windows-1252, the default for all other locales.
+func LocaleFallbackEncoding(locale string) *EncodingInfo {
+	subtags := strings.FieldsFunc(ASCIILower(locale), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(subtags) == 0 {
+		return Lookup("windows-1252")
+	}
+
+	if subtags[0] == "zh" {
+		for _, subtag := range subtags[1:] {
+			switch subtag {
+			case "hant", "tw", "hk", "mo":
+				return Lookup("big5")
+			}
+		}
+		return Lookup("gb18030")
+	}
+
+	if name, ok := localeFallbacks[subtags[0]]; ok {
+		return Lookup(name)
+	}
+	return Lookup("windows-1252")
+}
+
+// TLDFallbackEncoding returns the fallback encoding for content served from
+// a top-level domain, which may be given alone (".ru", "jp") or as the end
+// of a host name ("example.co.jp"). It returns nil for generic and unknown
+// domains, which say nothing about the encoding.
+func TLDFallbackEncoding(domain string) *EncodingInfo {
+	domain = strings.TrimSuffix(ASCIILower(domain), ".")
+	if i := strings.LastIndexByte(domain, '.'); i >= 0 {
+		domain = domain[i+1:]
+	}
+
+	locale, ok := tldLocales[domain]
+	if !ok {
+		return nil
+	}
+	return LocaleFallbackEncoding(locale)
+}
@@ -0,0 +1,83 @@
+package webencodings
+
+import "testing"
+
+func TestLocaleFallbackEncoding(t *testing.T) {
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"ru", "windows-1251"},
+		{"ru-RU", "windows-1251"},
+		{"uk_UA", "windows-1251"},
+		{"ja", "shift_jis"},
+		{"JA-jp", "shift_jis"},
+		{"ko-KR", "euc-kr"},
+		{"zh", "gb18030"},
+		{"zh-CN", "gb18030"},
+		{"zh-Hans-SG", "gb18030"},
+		{"zh-TW", "big5"},
+		{"zh-Hant", "big5"},
+		{"zh-HK", "big5"},
+		{"el", "iso-8859-7"},
+		{"he-IL", "windows-1255"},
+		{"th", "windows-874"},
+		{"tr", "windows-1254"},
+		{"vi", "windows-1258"},
+		{"pl", "iso-8859-2"},
+		{"cs", "windows-1250"},
+		{"lt", "windows-1257"},
+		{"sah", "windows-1251"},
+		{"en-US", "windows-1252"},
+		{"fr", "windows-1252"},
+		{"", "windows-1252"},
+		{"-", "windows-1252"},
+	}
+
+	for _, test := range tests {
+		result := LocaleFallbackEncoding(test.locale)
+		if result == nil || result.Name != test.expected {
+			t.Errorf("LocaleFallbackEncoding(%q): expected %s, got %v", test.locale, test.expected, result)
+		}
+	}
+}
+
+func TestTLDFallbackEncoding(t *testing.T) {
+	tests := []struct {
+		domain   string
+		expected string
+	}{
+		{"ru", "windows-1251"},
+		{".ru", "windows-1251"},
+		{"RU", "windows-1251"},
+		{"example.ru", "windows-1251"},
+		{"example.ru.", "windows-1251"},
+		{"рф", "windows-1251"},
+		{"пример.xn--p1ai", "windows-1251"},
+		{"jp", "shift_jis"},
+		{"www.example.co.jp", "shift_jis"},
+		{"cn", "gb18030"},
+		{"tw", "big5"},
+		{"hk", "big5"},
+		{"kr", "euc-kr"},
+		{"gr", "iso-8859-7"},
+		{"il", "windows-1255"},
+		{"eg", "windows-1256"},
+		{"vn", "windows-1258"},
+		{"cz", "windows-1250"},
+		{"ee", "windows-1257"},
+	}
+
+	for _, test := range tests {
+		result := TLDFallbackEncoding(test.domain)
+		if result == nil || result.Name != test.expected {
+			t.Errorf("TLDFallbackEncoding(%q): expected %s, got %v", test.domain, test.expected, result)
+		}
+	}
+
+	for _, domain := range []string{"com", "example.org", "fr", "", "."} {
+		if result := TLDFallbackEncoding(domain); result != nil {
+			t.Errorf("TLDFallbackEncoding(%q): expected nil, got %v", domain, result)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package webencodings
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FormField is a name and value pair of an HTML form. Forms are lists of
+// fields rather than maps, since names may repeat and order matters.
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// FormFieldsFromValues returns the fields of values, ordered by name as
+// url.Values.Encode orders them
+func FormFieldsFromValues(values url.Values) []FormField {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []FormField
+	for _, name := range names {
+		for _, value := range values[name] {
+			fields = append(fields, FormField{name, value})
+		}
+	}
+	return fields
+}
+
+// FormValues returns fields as url.Values, keeping the order of values that
+// share a name
+func FormValues(fields []FormField) url.Values {
+	values := make(url.Values)
+	for _, field := range fields {
+		values[field.Name] = append(values[field.Name], field.Value)
+	}
+	return values
+}
+
+// isFormCharsetField reports whether name is "_charset_", the special field
+// that form submission fills in with the name of the form's encoding
+func isFormCharsetField(name string) bool {
+	return ASCIILower(name) == "_charset_"
+}
+
+// normalizeNewlines replaces every CR not followed by LF, and every LF not
+// preceded by CR, with CRLF, as form submission does
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("\r\n")
+			i++
+		case s[i] == '\r' || s[i] == '\n':
+			b.WriteString("\r\n")
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// canonicalNames are the names of the Encoding Standard, as HTML form
+// submission writes them into "_charset_" fields, where they differ from
+// the lower cased names of this package
+var canonicalNames = map[string]string{
+	"utf-8":        "UTF-8",
+	"ibm866":       "IBM866",
+	"iso-8859-2":   "ISO-8859-2",
+	"iso-8859-3":   "ISO-8859-3",
+	"iso-8859-4":   "ISO-8859-4",
+	"iso-8859-5":   "ISO-8859-5",
+	"iso-8859-6":   "ISO-8859-6",
+	"iso-8859-7":   "ISO-8859-7",
+	"iso-8859-8":   "ISO-8859-8",
+	"iso-8859-8-i": "ISO-8859-8-I",
+	"iso-8859-10":  "ISO-8859-10",
+	"iso-8859-13":  "ISO-8859-13",
+	"iso-8859-14":  "ISO-8859-14",
+	"iso-8859-15":  "ISO-8859-15",
+	"iso-8859-16":  "ISO-8859-16",
+	"koi8-r":       "KOI8-R",
+	"koi8-u":       "KOI8-U",
+	"gbk":          "GBK",
+	"big5":         "Big5",
+	"euc-jp":       "EUC-JP",
+	"iso-2022-jp":  "ISO-2022-JP",
+	"shift_jis":    "Shift_JIS",
+	"euc-kr":       "EUC-KR",
+	"utf-16be":     "UTF-16BE",
+	"utf-16le":     "UTF-16LE",
+}
+
+// canonicalName returns the Encoding Standard name of encoding
+func canonicalName(encoding *EncodingInfo) string {
+	if name, ok := canonicalNames[encoding.Name]; ok {
+		return name
+	}
+	return encoding.Name
+}
+
+// EncodeForm serializes fields as application/x-www-form-urlencoded in the
+// form's encoding, as HTML form submission does. The encoding goes through
+// OutputEncoding, and characters it cannot represent are sent as
+// percent-encoded decimal character references. The value of a
+// "_charset_" field is replaced by the name of the encoding, such as
+// "UTF-8", and line breaks in names and values are normalized to CRLF. An
+// encoding that this package has no codec for, such as shift_jis, fails
+// with ErrUnsupportedEncoding.
+func EncodeForm(fields []FormField, encoding interface{}) (string, error) {
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+	enc = OutputEncoding(enc)
+	if !hasCodec(enc) {
+		return "", ErrUnsupportedEncoding
+	}
+
+	var output []byte
+	for i, field := range fields {
+		value := field.Value
+		if isFormCharsetField(field.Name) {
+			value = canonicalName(enc)
+		}
+
+		if i > 0 {
+			output = append(output, '&')
+		}
+		output = appendPercentEncode(output, normalizeNewlines(field.Name), enc, FormURLEncodedPercentEncodeSet, true)
+		output = append(output, '=')
+		output = appendPercentEncode(output, normalizeNewlines(value), enc, FormURLEncodedPercentEncodeSet, true)
+	}
+	return string(output), nil
+}
+
+// ParseForm parses an application/x-www-form-urlencoded body, decoding the
+// percent-escaped bytes of names and values with encoding. If encoding is
+// nil or "", the value of a "_charset_" field is used when it names a known
+// encoding, and UTF-8 otherwise. It returns the fields and the encoding they
+// were decoded with. errors defaults to "replace". An encoding that this
+// package has no codec for, whether passed in or named by "_charset_",
+// fails with ErrUnsupportedEncoding, though the encoding is still returned.
+func ParseForm(input string, encoding interface{}, errors string) ([]FormField, *EncodingInfo, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	type rawField struct {
+		name, value []byte
+	}
+	var raw []rawField
+	for _, sequence := range strings.Split(input, "&") {
+		if sequence == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(sequence, "=")
+		raw = append(raw, rawField{
+			PercentDecodeBytes(strings.ReplaceAll(name, "+", " ")),
+			PercentDecodeBytes(strings.ReplaceAll(value, "+", " ")),
+		})
+	}
+
+	var enc *EncodingInfo
+	if encoding != nil && encoding != "" {
+		var err error
+		if enc, err = getEncoding(encoding); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, field := range raw {
+			if isFormCharsetField(string(field.name)) {
+				enc = Lookup(string(field.value))
+				break
+			}
+		}
+		if enc == nil {
+			enc = UTF8
+		}
+	}
+	if !hasCodec(enc) {
+		return nil, enc, ErrUnsupportedEncoding
+	}
+
+	fields := make([]FormField, 0, len(raw))
+	for _, field := range raw {
+		name, err := decodeWith(field.name, enc, errors)
+		if err != nil {
+			return nil, enc, err
+		}
+		value, err := decodeWith(field.value, enc, errors)
+		if err != nil {
+			return nil, enc, err
+		}
+		fields = append(fields, FormField{name, value})
+	}
+	return fields, enc, nil
+}
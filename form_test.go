@@ -0,0 +1,120 @@
+package webencodings
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeForm(t *testing.T) {
+	tests := []struct {
+		fields   []FormField
+		encoding string
+		expected string
+	}{
+		{[]FormField{{"q", "a b"}, {"lang", "en"}}, "utf-8", "q=a+b&lang=en"},
+		{[]FormField{{"q", "é"}}, "utf-8", "q=%C3%A9"},
+		{[]FormField{{"q", "é"}}, "utf-16le", "q=%C3%A9"},
+		{[]FormField{{"q", "é\uf780"}}, "x-user-defined", "q=%26%23233%3B%80"},
+		{[]FormField{{"a&b", "c=d+e"}}, "utf-8", "a%26b=c%3Dd%2Be"},
+		{[]FormField{{"_charset_", ""}, {"q", "x"}}, "x-user-defined", "_charset_=x-user-defined&q=x"},
+		{[]FormField{{"_CHARSET_", ""}}, "utf-16be", "_CHARSET_=UTF-8"},
+		{[]FormField{{"_charset_", "replaced"}}, "utf-8", "_charset_=UTF-8"},
+		{[]FormField{{"text", "a\rb\nc\r\nd"}}, "utf-8", "text=a%0D%0Ab%0D%0Ac%0D%0Ad"},
+		{[]FormField{{"x", ""}, {"x", "1"}}, "utf-8", "x=&x=1"},
+		{nil, "utf-8", ""},
+	}
+
+	for _, test := range tests {
+		result, err := EncodeForm(test.fields, test.encoding)
+		if err != nil {
+			t.Errorf("EncodeForm(%v, %s): unexpected error %v", test.fields, test.encoding, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("EncodeForm(%v, %s): expected %q, got %q", test.fields, test.encoding, test.expected, result)
+		}
+	}
+
+	if _, err := EncodeForm(nil, "bogus"); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+	if _, err := EncodeForm([]FormField{{"_charset_", ""}}, "sjis"); err != ErrUnsupportedEncoding {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	tests := []struct {
+		input    string
+		encoding interface{}
+		expected []FormField
+		name     string
+	}{
+		{"q=a+b&lang=en", "utf-8", []FormField{{"q", "a b"}, {"lang", "en"}}, "utf-8"},
+		{"a&&b=&=c&d=e=f", nil, []FormField{{"a", ""}, {"b", ""}, {"", "c"}, {"d", "e=f"}}, "utf-8"},
+		{"q=%C3%A9%2B", "", []FormField{{"q", "é+"}}, "utf-8"},
+		{"q=%80", "x-user-defined", []FormField{{"q", "\uf780"}}, "x-user-defined"},
+		{"q=%80&_charset_=x-user-defined", nil, []FormField{{"q", "\uf780"}, {"_charset_", "x-user-defined"}}, "x-user-defined"},
+		{"q=%80&_charset_=bogus", nil, []FormField{{"q", "\uFFFD"}, {"_charset_", "bogus"}}, "utf-8"},
+		{"q=%EF%BB%BFa", "utf-8", []FormField{{"q", "\uFEFFa"}}, "utf-8"},
+	}
+
+	for _, test := range tests {
+		fields, encoding, err := ParseForm(test.input, test.encoding, "")
+		if err != nil {
+			t.Errorf("ParseForm(%q): unexpected error %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(fields, test.expected) || encoding.Name != test.name {
+			t.Errorf("ParseForm(%q): expected %v in %s, got %v in %s", test.input, test.expected, test.name, fields, encoding.Name)
+		}
+	}
+
+	if _, _, err := ParseForm("q=%FF", "utf-8", "strict"); err == nil {
+		t.Errorf("Expected an error parsing invalid UTF-8 in strict mode")
+	}
+
+	// Encodings without a codec are refused, even when named by _charset_
+	for _, test := range []struct {
+		input    string
+		encoding interface{}
+		name     string
+	}{
+		{"q=%82%A0", "shift_jis", "shift_jis"},
+		{"q=%82%A0&_charset_=Shift_JIS", nil, "shift_jis"},
+		{"q=%E9&_charset_=windows-1252", "", "windows-1252"},
+	} {
+		fields, encoding, err := ParseForm(test.input, test.encoding, "")
+		if err != ErrUnsupportedEncoding || fields != nil || encoding == nil || encoding.Name != test.name {
+			t.Errorf("ParseForm(%q): expected ErrUnsupportedEncoding in %s, got %v in %v (%v)", test.input, test.name, fields, encoding, err)
+		}
+	}
+}
+
+func TestFormRoundTrip(t *testing.T) {
+	fields := []FormField{{"name", "Zoë & co"}, {"note", "50% off!\r\n"}, {"x", "\uf7f7"}}
+	for _, encoding := range []string{"utf-8", "x-user-defined"} {
+		encoded, _ := EncodeForm(fields, encoding)
+		parsed, _, err := ParseForm(encoded, encoding, "strict")
+		if encoding == "x-user-defined" {
+			// ë cannot be encoded and comes back as a character reference
+			fields[0].Value = "Zo&#235; & co"
+		}
+		if err != nil || !reflect.DeepEqual(parsed, fields) {
+			t.Errorf("round trip through %s: expected %v, got %v (%v)", encoding, fields, parsed, err)
+		}
+	}
+}
+
+func TestFormValues(t *testing.T) {
+	values := url.Values{"b": {"2", "3"}, "a": {"1"}}
+	fields := FormFieldsFromValues(values)
+	expected := []FormField{{"a", "1"}, {"b", "2"}, {"b", "3"}}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("FormFieldsFromValues: expected %v, got %v", expected, fields)
+	}
+	if !reflect.DeepEqual(FormValues(fields), values) {
+		t.Errorf("FormValues: expected %v, got %v", values, FormValues(fields))
+	}
+}
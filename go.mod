@@ -1,3 +1,3 @@
 module github.com/stackquest-hq/webencodings
 
-go 1.22.3
+go 1.23
@@ -0,0 +1,333 @@
+package webencodings
+
+import "bytes"
+
+// PrescanLimit is the number of bytes PrescanHTML looks at
+const PrescanLimit = 1024
+
+// isHTMLSpace reports whether b is ASCII whitespace as the HTML prescan
+// algorithm defines it
+func isHTMLSpace(b byte) bool {
+	return b == '\t' || b == '\n' || b == '\f' || b == '\r' || b == ' '
+}
+
+// isASCIIAlpha reports whether b is an ASCII letter
+func isASCIIAlpha(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// asciiLowerByte maps A-Z to a-z and leaves every other byte alone
+func asciiLowerByte(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 32
+	}
+	return b
+}
+
+// hasPrefixFold reports whether s begins with the lower case ASCII prefix,
+// ignoring ASCII case in s
+func hasPrefixFold(s []byte, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if asciiLowerByte(s[i]) != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PrescanHTML implements the HTML standard's "prescan a byte stream to
+// determine its encoding" algorithm over the first PrescanLimit bytes of
+// input. It returns nil if no usable <meta> declaration was found.
+// UTF-16 declarations are reported as UTF-8 and x-user-defined as
+// windows-1252, as the spec requires.
+func PrescanHTML(input []byte) *EncodingInfo {
+	if len(input) > PrescanLimit {
+		input = input[:PrescanLimit]
+	}
+
+	for pos := 0; pos < len(input); pos++ {
+		rest := input[pos:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			// Skip to the end of the comment; "<!-->" is a complete comment
+			end := bytes.Index(input[pos+2:], []byte("-->"))
+			if end < 0 {
+				return nil
+			}
+			pos += 2 + end + 2
+
+		case hasPrefixFold(rest, "<meta") && len(rest) > 5 && (isHTMLSpace(rest[5]) || rest[5] == '/'):
+			encoding, next, ok := prescanMeta(input, pos+6)
+			if !ok {
+				return nil
+			}
+			if encoding != nil {
+				return encoding
+			}
+			pos = next
+
+		case len(rest) > 1 && rest[0] == '<' && (isASCIIAlpha(rest[1]) || (rest[1] == '/' && len(rest) > 2 && isASCIIAlpha(rest[2]))):
+			// Skip the tag name, then all its attributes
+			pos++
+			for pos < len(input) && !isHTMLSpace(input[pos]) && input[pos] != '>' {
+				pos++
+			}
+			for {
+				name, _, next, ok := prescanAttribute(input, pos)
+				if !ok {
+					return nil
+				}
+				pos = next
+				if name == nil {
+					break
+				}
+			}
+
+		case bytes.HasPrefix(rest, []byte("<!")) || bytes.HasPrefix(rest, []byte("</")) || bytes.HasPrefix(rest, []byte("<?")):
+			end := bytes.IndexByte(rest[2:], '>')
+			if end < 0 {
+				return nil
+			}
+			pos += 2 + end
+		}
+	}
+
+	return nil
+}
+
+// prescanMeta processes the attributes of a <meta> tag starting at pos.
+// It returns the declared encoding, if any, and the position after the tag.
+// ok is false if the prescan ran out of input.
+func prescanMeta(input []byte, pos int) (encoding *EncodingInfo, next int, ok bool) {
+	seen := make(map[string]bool)
+	gotPragma := false
+	needPragma := 0 // 0 is unset, 1 is false and 2 is true
+	var charset *EncodingInfo
+	// charsetFailed is set when a charset attribute names no encoding, which
+	// a later content attribute cannot undo
+	charsetFailed := false
+
+	for {
+		name, value, next, ok := prescanAttribute(input, pos)
+		if !ok {
+			return nil, 0, false
+		}
+		pos = next
+		if name == nil {
+			break
+		}
+		if seen[string(name)] {
+			continue
+		}
+		seen[string(name)] = true
+
+		switch string(name) {
+		case "http-equiv":
+			if string(value) == "content-type" {
+				gotPragma = true
+			}
+		case "content":
+			if charset == nil && !charsetFailed {
+				if enc := ExtractMetaCharset(string(value)); enc != nil {
+					charset = enc
+					needPragma = 2
+				}
+			}
+		case "charset":
+			charset = Lookup(string(value))
+			charsetFailed = charset == nil
+			needPragma = 1
+		}
+	}
+
+	if needPragma == 0 || (needPragma == 2 && !gotPragma) || charset == nil {
+		return nil, pos, true
+	}
+	switch charset.Name {
+	case "utf-16be", "utf-16le":
+		charset = UTF8
+	case "x-user-defined":
+		charset = Lookup("windows-1252")
+	}
+	return charset, pos, true
+}
+
+// prescanAttribute implements the prescan's "get an attribute" algorithm.
+// Names and values are lower cased. A nil name means there are no more
+// attributes, and ok is false if the input ended first.
+func prescanAttribute(input []byte, pos int) (name, value []byte, next int, ok bool) {
+	for pos < len(input) && (isHTMLSpace(input[pos]) || input[pos] == '/') {
+		pos++
+	}
+	if pos >= len(input) {
+		return nil, nil, pos, false
+	}
+	if input[pos] == '>' {
+		return nil, nil, pos, true
+	}
+
+	name = []byte{}
+	value = []byte{}
+
+	// Attribute name, up to "=" possibly preceded by spaces
+	for {
+		if pos >= len(input) {
+			return nil, nil, pos, false
+		}
+		b := input[pos]
+		if b == '=' && len(name) > 0 {
+			break
+		}
+		if isHTMLSpace(b) {
+			for pos < len(input) && isHTMLSpace(input[pos]) {
+				pos++
+			}
+			if pos >= len(input) {
+				return nil, nil, pos, false
+			}
+			if input[pos] != '=' {
+				return name, value, pos, true
+			}
+			break
+		}
+		if b == '/' || b == '>' {
+			return name, value, pos, true
+		}
+		name = append(name, asciiLowerByte(b))
+		pos++
+	}
+
+	// Attribute value
+	pos++
+	for pos < len(input) && isHTMLSpace(input[pos]) {
+		pos++
+	}
+	if pos >= len(input) {
+		return nil, nil, pos, false
+	}
+
+	switch b := input[pos]; b {
+	case '"', '\'':
+		for pos++; pos < len(input); pos++ {
+			if input[pos] == b {
+				return name, value, pos + 1, true
+			}
+			value = append(value, asciiLowerByte(input[pos]))
+		}
+		return nil, nil, pos, false
+	case '>':
+		return name, value, pos, true
+	}
+
+	for ; pos < len(input); pos++ {
+		b := input[pos]
+		if isHTMLSpace(b) || b == '>' {
+			return name, value, pos, true
+		}
+		value = append(value, asciiLowerByte(b))
+	}
+	return nil, nil, pos, false
+}
+
+// ExtractMetaCharset implements the HTML standard's "extracting a
+// character encoding from a meta element" for the value of a content
+// attribute, such as "text/html; charset=windows-1252"
+func ExtractMetaCharset(content string) *EncodingInfo {
+	s := []byte(content)
+	for pos := 0; ; {
+		i := indexFold(s[pos:], "charset")
+		if i < 0 {
+			return nil
+		}
+		pos += i + len("charset")
+
+		for pos < len(s) && isHTMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) || s[pos] != '=' {
+			continue
+		}
+		pos++
+		for pos < len(s) && isHTMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) {
+			return nil
+		}
+
+		if q := s[pos]; q == '"' || q == '\'' {
+			end := bytes.IndexByte(s[pos+1:], q)
+			if end < 0 {
+				return nil
+			}
+			return Lookup(string(s[pos+1 : pos+1+end]))
+		}
+
+		end := pos
+		for end < len(s) && !isHTMLSpace(s[end]) && s[end] != ';' {
+			end++
+		}
+		return Lookup(string(s[pos:end]))
+	}
+}
+
+// indexFold returns the index of the first ASCII case-insensitive match of
+// the lower case needle in s, or -1
+func indexFold(s []byte, needle string) int {
+	for i := 0; i+len(needle) <= len(s); i++ {
+		if hasPrefixFold(s[i:], needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// DecodeHTML decodes an HTML document, determining its encoding in the
+// order the HTML standard gives: BOM, then the transport layer encoding
+// (such as a Content-Type charset), then a <meta> prescan, then the
+// fallback encoding. transportEncoding may be nil or "" when there is none;
+// unknown transport labels are ignored. An encoding that this package has
+// no codec for, such as shift_jis, fails with ErrUnsupportedEncoding, though
+// the encoding is still returned.
+func DecodeHTML(input []byte, transportEncoding interface{}, fallbackEncoding interface{}, errors string) (string, *EncodingInfo, error) {
+	decoded, result, err := DecodeHTMLWithResult(input, transportEncoding, fallbackEncoding, errors)
+	if result == nil {
+		return decoded, nil, err
+	}
+	return decoded, result.Encoding, err
+}
+
+// DecodeHTMLWithResult is like DecodeHTML but reports which step chose the
+// encoding and how certain it is. The result is returned alongside
+// ErrUnsupportedEncoding too.
+func DecodeHTMLWithResult(input []byte, transportEncoding interface{}, fallbackEncoding interface{}, errors string) (string, *DecodeResult, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	// Fail early if encoding is invalid
+	fallbackEnc, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, remaining := sniffBOM(input)
+	if result == nil && transportEncoding != nil && transportEncoding != "" {
+		if encoding, _ := getEncoding(transportEncoding); encoding != nil {
+			result = newDecodeResult(encoding, SourceTransport)
+		}
+	}
+	if result == nil {
+		if encoding := PrescanHTML(input); encoding != nil {
+			result = newDecodeResult(encoding, SourcePrescan)
+		}
+	}
+	if result == nil {
+		result = newDecodeResult(fallbackEnc, SourceFallback)
+	}
+
+	return decodeWithResult(remaining, result, errors)
+}
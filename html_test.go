@@ -0,0 +1,134 @@
+package webencodings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrescanHTML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<meta charset="windows-1251">`, "windows-1251"},
+		{`<META CHARSET=KOI8-R>`, "koi8-r"},
+		{`<meta charset='shift_jis'/>`, "shift_jis"},
+		{`<meta/charset=euc-jp>`, "euc-jp"},
+		{`<meta charset = " gbk ">`, "gbk"},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-2">`, "iso-8859-2"},
+		{`<meta content="text/html; charset='big5'" http-equiv=content-type>`, "big5"},
+		{`<meta content="charset=iso-8859-5; charset=koi8-u" http-equiv="content-type">`, "iso-8859-5"},
+		{`<meta charset="utf-16le">`, "utf-8"},
+		{`<meta charset="utf-16">`, "utf-8"},
+		{`<meta charset="x-user-defined">`, "windows-1252"},
+		{`<meta charset="bogus"><meta charset="koi8-r">`, "koi8-r"},
+		{`<meta charset="koi8-r" charset="big5">`, "koi8-r"},
+		{`<meta content="charset=koi8-r"><meta charset="big5">`, "big5"},
+		{`<html lang=ru><head><title>x</title><meta charset=windows-1251>`, "windows-1251"},
+		{`<p title="<meta charset=big5>"><meta charset=koi8-r>`, "koi8-r"},
+		{`<!-- <meta charset=big5> --><meta charset=koi8-r>`, "koi8-r"},
+		{`<!--><meta charset=koi8-r>`, "koi8-r"},
+		{`<!DOCTYPE html><?xml foo?></p><meta charset=gbk>`, "gbk"},
+
+		// No usable declaration
+		{``, ""},
+		{`<meta>`, ""},
+		{`<metacharset=big5>`, ""},
+		{`<meta content="text/html; charset=big5">`, ""},
+		{`<meta http-equiv="content-type" content="text/html">`, ""},
+		{`<meta charset="bogus" http-equiv="content-type" content="text/html; charset=koi8-r">`, ""},
+		{`<meta http-equiv="content-type" content="text/html; charset=koi8-r" charset="bogus">`, ""},
+		{`<meta charset="big5`, ""},
+		{`<!-- <meta charset=big5>`, ""},
+		{`<meta charset="bogus">`, ""},
+		{strings.Repeat(" ", PrescanLimit) + `<meta charset=big5>`, ""},
+	}
+
+	for _, test := range tests {
+		encoding := PrescanHTML([]byte(test.input))
+		if test.expected == "" {
+			if encoding != nil {
+				t.Errorf("PrescanHTML(%q) = %v, expected nil", test.input, encoding)
+			}
+		} else if encoding == nil || encoding.Name != test.expected {
+			t.Errorf("PrescanHTML(%q) = %v, expected %s", test.input, encoding, test.expected)
+		}
+	}
+}
+
+func TestExtractMetaCharset(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"text/html; charset=koi8-r", "koi8-r"},
+		{"text/html; CHARSET = \"big5\"", "big5"},
+		{"text/html; charset='gbk'; foo", "gbk"},
+		{"charset;charset=euc-kr", "euc-kr"},
+		{"text/html; charset=\"big5", ""},
+		{"text/html; charset=", ""},
+		{"text/html", ""},
+	}
+
+	for _, test := range tests {
+		encoding := ExtractMetaCharset(test.input)
+		if test.expected == "" {
+			if encoding != nil {
+				t.Errorf("ExtractMetaCharset(%q) = %v, expected nil", test.input, encoding)
+			}
+		} else if encoding == nil || encoding.Name != test.expected {
+			t.Errorf("ExtractMetaCharset(%q) = %v, expected %s", test.input, encoding, test.expected)
+		}
+	}
+}
+
+func TestDecodeHTML(t *testing.T) {
+	doc := []byte("<meta charset=x-user-defined>")
+
+	tests := []struct {
+		input     []byte
+		transport interface{}
+		expected  string
+		err       error
+	}{
+		// BOM beats everything
+		{append([]byte{0xef, 0xbb, 0xbf}, doc...), "koi8-r", "utf-8", nil},
+		// Transport layer beats the prescan
+		{doc, "utf-16le", "utf-16le", nil},
+		{doc, "koi8-r", "koi8-r", ErrUnsupportedEncoding},
+		{doc, Lookup("big5"), "big5", ErrUnsupportedEncoding},
+		// Unknown or missing transport labels fall through to the prescan
+		{doc, "bogus", "windows-1252", ErrUnsupportedEncoding},
+		{doc, nil, "windows-1252", ErrUnsupportedEncoding},
+		{doc, "", "windows-1252", ErrUnsupportedEncoding},
+		{[]byte("<meta charset=shift_jis>"), nil, "shift_jis", ErrUnsupportedEncoding},
+		{[]byte("<meta charset=utf-8>"), nil, "utf-8", nil},
+		// Otherwise the fallback is used
+		{[]byte("<p>hello</p>"), nil, "x-user-defined", nil},
+	}
+
+	for _, test := range tests {
+		decoded, encoding, err := DecodeHTML(test.input, test.transport, "x-user-defined", "")
+		if err != test.err {
+			t.Errorf("DecodeHTML(%q, %v): expected error %v, got %v", test.input, test.transport, test.err, err)
+		}
+		if encoding == nil || encoding.Name != test.expected {
+			t.Errorf("DecodeHTML(%q, %v): expected %s, got %v", test.input, test.transport, test.expected, encoding)
+		}
+		if err != nil && decoded != "" {
+			t.Errorf("DecodeHTML(%q, %v): expected no output with an error, got %q", test.input, test.transport, decoded)
+		}
+	}
+
+	decoded, _, err := DecodeHTML([]byte{'a', 0xf7}, nil, "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeHTML failed: %v", err)
+	}
+	if decoded != "a" {
+		t.Errorf("Expected %q, got %q", "a", decoded)
+	}
+
+	if _, _, err := DecodeHTML(doc, nil, "invalid", ""); err == nil {
+		t.Error("Expected error for invalid fallback encoding label in DecodeHTML")
+	}
+}
@@ -0,0 +1,271 @@
+package webencodings
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// transcodeTarget reports whether a response with header is text that
+// should be transcoded, and whether it is HTML, which is prescanned for a
+// <meta> charset. Compressed bodies are left alone.
+func transcodeTarget(header http.Header) (ok bool, html bool) {
+	if header.Get("Content-Encoding") != "" {
+		return false, false
+	}
+	mt := parseMIMEType(header.Get("Content-Type"))
+	if mt == nil || !strings.HasPrefix(mt.essence, "text/") {
+		return false, false
+	}
+	return true, mt.essence == "text/html"
+}
+
+// setUTF8ContentType rewrites the charset parameter of the Content-Type in
+// header to utf-8, and drops the Content-Length the transcoding invalidates
+func setUTF8ContentType(header http.Header) {
+	mt := parseMIMEType(header.Get("Content-Type"))
+	mt.params["charset"] = "utf-8"
+	contentType := mime.FormatMediaType(mt.essence, mt.params)
+	if contentType == "" {
+		contentType = mt.essence + "; charset=utf-8"
+	}
+	header.Set("Content-Type", contentType)
+	header.Del("Content-Length")
+}
+
+// responseEncoding returns the encoding a text response body is decoded
+// with before its BOM is sniffed: the Content-Type charset, then for HTML
+// a <meta> charset in head, then fallback
+func responseEncoding(header http.Header, html bool, head []byte, fallback *EncodingInfo) *EncodingInfo {
+	if encoding, _ := ContentTypeEncoding(header.Get("Content-Type")); encoding != nil {
+		return encoding
+	}
+	if html {
+		if encoding := PrescanHTML(head); encoding != nil {
+			return encoding
+		}
+	}
+	return fallback
+}
+
+// TranscodeHandler wraps h so that text responses are served as UTF-8.
+// The encoding of a response with a text/* Content-Type is determined from
+// its BOM, its charset parameter, a <meta> prescan for text/html, and
+// finally fallbackEncoding. The body is decoded as it is written, the
+// charset is rewritten to utf-8 and Content-Length is removed. Responses
+// without a Content-Type, with a Content-Encoding, or in an encoding this
+// package has no codec for pass through as they are.
+func TranscodeHandler(h http.Handler, fallbackEncoding interface{}) (http.Handler, error) {
+	fallback, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &transcodeWriter{ResponseWriter: w, fallback: fallback}
+		defer tw.finish()
+		h.ServeHTTP(tw, r)
+	}), nil
+}
+
+// transcodeWriter is the http.ResponseWriter of TranscodeHandler
+type transcodeWriter struct {
+	http.ResponseWriter
+	fallback *EncodingInfo
+	// status is the status code passed to WriteHeader, or 0
+	status int
+	// html is set while the head of an HTML body is buffered for the prescan
+	html    bool
+	head    []byte
+	decoder *IncrementalDecoder
+}
+
+// WriteHeader records the status code. The header is sent once the
+// encoding is known, which for HTML may take the first PrescanLimit bytes.
+func (tw *transcodeWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		// Informational responses go out as they are
+		tw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if tw.status != 0 {
+		return
+	}
+	tw.status = status
+
+	ok, html := transcodeTarget(tw.Header())
+	if !ok || status == http.StatusNoContent || status == http.StatusNotModified {
+		tw.sendHeader()
+		return
+	}
+	if encoding, _ := ContentTypeEncoding(tw.Header().Get("Content-Type")); encoding == nil && html {
+		tw.html = true
+		return
+	}
+	tw.start()
+}
+
+// start chooses the encoding and sends the header with a UTF-8 charset.
+// For HTML it then writes the buffered head of the body. A body in an
+// encoding without a codec is sent as it is, under its own header.
+func (tw *transcodeWriter) start() error {
+	encoding := responseEncoding(tw.Header(), tw.html, tw.head, tw.fallback)
+	if hasCodec(encoding) {
+		tw.decoder, _ = NewIncrementalDecoder(encoding, "replace")
+		setUTF8ContentType(tw.Header())
+	}
+	tw.sendHeader()
+
+	head := tw.head
+	tw.html = false
+	tw.head = nil
+	if len(head) > 0 {
+		_, err := tw.write(head, false)
+		return err
+	}
+	return nil
+}
+
+// sendHeader writes the recorded status code and header
+func (tw *transcodeWriter) sendHeader() {
+	tw.ResponseWriter.WriteHeader(tw.status)
+}
+
+// Write decodes p and writes the UTF-8 result
+func (tw *transcodeWriter) Write(p []byte) (int, error) {
+	if tw.status == 0 {
+		tw.WriteHeader(http.StatusOK)
+	}
+
+	if tw.html {
+		tw.head = append(tw.head, p...)
+		if len(tw.head) < PrescanLimit {
+			return len(p), nil
+		}
+		if err := tw.start(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	return tw.write(p, false)
+}
+
+// write decodes p if transcoding, and writes it to ResponseWriter
+func (tw *transcodeWriter) write(p []byte, final bool) (int, error) {
+	if tw.decoder == nil {
+		return tw.ResponseWriter.Write(p)
+	}
+
+	decoded, err := tw.decoder.Decode(p, final)
+	if err != nil {
+		return 0, err
+	}
+	if decoded != "" {
+		if _, err := io.WriteString(tw.ResponseWriter, decoded); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered head of an HTML body, deciding its encoding from
+// what has been written so far, and flushes ResponseWriter if it can
+func (tw *transcodeWriter) Flush() {
+	if tw.status == 0 {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.html {
+		tw.start()
+	}
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (tw *transcodeWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// finish flushes the decoder once the handler has returned
+func (tw *transcodeWriter) finish() {
+	if tw.status == 0 {
+		// Nothing was written; let net/http send its defaults
+		return
+	}
+	if tw.html {
+		tw.start()
+	}
+	if tw.decoder != nil {
+		tw.write(nil, true)
+	}
+}
+
+// TranscodeTransport wraps base so that text responses are returned as
+// UTF-8, determining their encoding as TranscodeHandler does. base defaults
+// to http.DefaultTransport. The body is decoded as it is read.
+func TranscodeTransport(base http.RoundTripper, fallbackEncoding interface{}) (http.RoundTripper, error) {
+	fallback, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transcodeTransport{base: base, fallback: fallback}, nil
+}
+
+// transcodeTransport is the http.RoundTripper of TranscodeTransport
+type transcodeTransport struct {
+	base     http.RoundTripper
+	fallback *EncodingInfo
+}
+
+// RoundTrip sends req with the base transport and transcodes the response
+func (t *transcodeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody || req.Method == http.MethodHead {
+		return resp, err
+	}
+
+	ok, html := transcodeTarget(resp.Header)
+	if !ok {
+		return resp, nil
+	}
+
+	body := bufio.NewReaderSize(resp.Body, PrescanLimit)
+	var head []byte
+	if html {
+		// Peek returns what it can before EOF, which is all the prescan needs
+		head, _ = body.Peek(PrescanLimit)
+	}
+	encoding := responseEncoding(resp.Header, html, head, t.fallback)
+	if !hasCodec(encoding) {
+		// Leave the body as it is, but keep what the prescan read
+		resp.Body = &transcodeBody{Reader: body, body: resp.Body}
+		return resp, nil
+	}
+	decoder, _ := NewIncrementalDecoder(encoding, "replace")
+
+	resp.Body = &transcodeBody{
+		Reader: newDecodeReader(body, decoder.Decode),
+		body:   resp.Body,
+	}
+	setUTF8ContentType(resp.Header)
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+// transcodeBody is a transcoded response body that closes the original
+type transcodeBody struct {
+	io.Reader
+	body io.Closer
+}
+
+// Close closes the original body
+func (b *transcodeBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,118 @@
+package webencodings
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// httpTests are responses and the UTF-8 text they should be transcoded to
+// with an x-user-defined fallback. Responses are transcoded when their
+// resultType has a utf-8 charset.
+var httpTests = []struct {
+	name        string
+	contentType string
+	body        string
+	expected    string
+	resultType  string
+}{
+	{"charset", "text/html; charset=utf-8", "<p>\xc3\xa9", "<p>é", "text/html; charset=utf-8"},
+	{"quoted charset", `text/plain; charset="utf-8"; format=flowed`, "\xc3\xa9", "é", "text/plain; charset=utf-8; format=flowed"},
+	{"BOM", "text/html; charset=x-user-defined", "\xFF\xFEa\x00", "a", "text/html; charset=utf-8"},
+	{"prescan", "text/html", "<meta charset=utf-16le>\xc3\xa9", "<meta charset=utf-16le>é", "text/html; charset=utf-8"},
+	{"late meta", "text/html", strings.Repeat(" ", PrescanLimit) + "<meta charset=utf-8>\x80", strings.Repeat(" ", PrescanLimit) + "<meta charset=utf-8>\uf780", "text/html; charset=utf-8"},
+	{"fallback", "text/plain", "a\x80", "a\uf780", "text/plain; charset=utf-8"},
+	{"not text", "image/png", "\x89PNG\x80", "\x89PNG\x80", "image/png"},
+
+	// Encodings without a codec are left alone rather than mislabeled
+	{"windows-1251", "text/plain; charset=windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "\xcf\xf0\xe8\xe2\xe5\xf2", "text/plain; charset=windows-1251"},
+	{"shift_jis meta", "text/html", "<meta charset=shift_jis>\x82\xa0", "<meta charset=shift_jis>\x82\xa0", "text/html"},
+	{"windows-1252 meta", "text/html", "<meta charset=x-user-defined>caf\xe9", "<meta charset=x-user-defined>caf\xe9", "text/html"},
+}
+
+func TestTranscodeHandler(t *testing.T) {
+	for _, test := range httpTests {
+		handler, err := TranscodeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", test.contentType)
+			w.Header().Set("Content-Length", "999")
+			// Write a byte at a time, splitting every sequence
+			for i := 0; i < len(test.body); i++ {
+				w.Write([]byte{test.body[i]})
+			}
+		}), "x-user-defined")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+		if body := recorder.Body.String(); body != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, body)
+		}
+		if contentType := recorder.Header().Get("Content-Type"); contentType != test.resultType {
+			t.Errorf("%s: expected Content-Type %q, got %q", test.name, test.resultType, contentType)
+		}
+		if transcoded := strings.Contains(test.resultType, "charset=utf-8"); transcoded == (recorder.Header().Get("Content-Length") != "") {
+			t.Errorf("%s: unexpected Content-Length %q", test.name, recorder.Header().Get("Content-Length"))
+		}
+	}
+
+	if _, err := TranscodeHandler(http.NotFoundHandler(), "bogus"); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+}
+
+func TestTranscodeHandlerStatus(t *testing.T) {
+	handler, _ := TranscodeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "<meta charset=utf-8>\xc3")
+		// Flushing decides the encoding from what has been written so far
+		w.(http.Flusher).Flush()
+		w.Write([]byte("\xa9"))
+	}), "x-user-defined")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusTeapot || recorder.Body.String() != "<meta charset=utf-8>é" || !recorder.Flushed {
+		t.Errorf("unexpected response %d %q, flushed %v", recorder.Code, recorder.Body.String(), recorder.Flushed)
+	}
+}
+
+func TestTranscodeTransport(t *testing.T) {
+	for _, test := range httpTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", test.contentType)
+			io.WriteString(w, test.body)
+		}))
+
+		transport, err := TranscodeTransport(nil, "x-user-defined")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: transport}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		server.Close()
+
+		if err != nil || string(body) != test.expected {
+			t.Errorf("%s: expected %q, got %q (%v)", test.name, test.expected, body, err)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != test.resultType {
+			t.Errorf("%s: expected Content-Type %q, got %q", test.name, test.resultType, contentType)
+		}
+		if transcoded := strings.Contains(test.resultType, "charset=utf-8"); transcoded != (resp.ContentLength == -1 && resp.Header.Get("Content-Length") == "") {
+			t.Errorf("%s: unexpected Content-Length %d", test.name, resp.ContentLength)
+		}
+	}
+
+	if _, err := TranscodeTransport(nil, "bogus"); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+}
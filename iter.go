@@ -0,0 +1,118 @@
+package webencodings
+
+import (
+	"io"
+	"iter"
+)
+
+// readChunkSize is the buffer size used when pulling chunks from an io.Reader
+const readChunkSize = 4096
+
+// DecodeSeq provides "pull"-based decoding over a sequence of byte chunks.
+// It returns the sequence of decoded strings and a function reporting the
+// encoding in use, which is nil until the BOM has been sniffed.
+func DecodeSeq(input iter.Seq[[]byte], fallbackEncoding interface{}, errors string) (iter.Seq2[string, error], func() *EncodingInfo, error) {
+	decoder, err := NewIncrementalDecoder(fallbackEncoding, errors)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seq := func(yield func(string, error) bool) {
+		for chunk := range input {
+			decoded, err := decoder.Decode(chunk, false)
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if decoded != "" && !yield(decoded, nil) {
+				return
+			}
+		}
+
+		decoded, err := decoder.Decode(nil, true)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		if decoded != "" {
+			yield(decoded, nil)
+		}
+	}
+
+	return seq, func() *EncodingInfo { return decoder.Encoding }, nil
+}
+
+// DecodeReaderSeq is like DecodeSeq but pulls its chunks from r
+func DecodeReaderSeq(r io.Reader, fallbackEncoding interface{}, errors string) (iter.Seq2[string, error], func() *EncodingInfo, error) {
+	decoder, err := NewIncrementalDecoder(fallbackEncoding, errors)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seq := func(yield func(string, error) bool) {
+		buf := make([]byte, readChunkSize)
+		for {
+			n, readErr := r.Read(buf)
+			if n > 0 {
+				decoded, err := decoder.Decode(buf[:n], false)
+				if err != nil {
+					yield("", err)
+					return
+				}
+				if decoded != "" && !yield(decoded, nil) {
+					return
+				}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				yield("", readErr)
+				return
+			}
+		}
+
+		decoded, err := decoder.Decode(nil, true)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		if decoded != "" {
+			yield(decoded, nil)
+		}
+	}
+
+	return seq, func() *EncodingInfo { return decoder.Encoding }, nil
+}
+
+// EncodeSeq provides "pull"-based encoding over a sequence of strings
+func EncodeSeq(input iter.Seq[string], encoding interface{}, errors string) (iter.Seq2[[]byte, error], error) {
+	encoder, err := NewIncrementalEncoder(encoding, errors)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := func(yield func([]byte, error) bool) {
+		for chunk := range input {
+			encoded, err := encoder.Encode(chunk, false)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(encoded) > 0 && !yield(encoded, nil) {
+				return
+			}
+		}
+
+		encoded, err := encoder.Encode("", true)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if len(encoded) > 0 {
+			yield(encoded, nil)
+		}
+	}
+
+	return seq, nil
+}
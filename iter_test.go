@@ -0,0 +1,144 @@
+package webencodings
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeSeq(t *testing.T) {
+	chunks := [][]byte{{0xef}, {0xbb}, {0xbf, 'a'}, {'b', 'c'}}
+	seq, encoding, err := DecodeSeq(slices.Values(chunks), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeSeq failed: %v", err)
+	}
+	if encoding() != nil {
+		t.Errorf("Expected no encoding before iterating, got %v", encoding())
+	}
+
+	var result strings.Builder
+	for decoded, err := range seq {
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		result.WriteString(decoded)
+	}
+	if result.String() != "abc" {
+		t.Errorf("Expected %q, got %q", "abc", result.String())
+	}
+	if encoding() == nil || encoding().Name != "utf-8" {
+		t.Errorf("Expected utf-8 from BOM, got %v", encoding())
+	}
+
+	// No BOM falls back to x-user-defined
+	seq, encoding, err = DecodeSeq(slices.Values([][]byte{{'a', 0xf7}}), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeSeq failed: %v", err)
+	}
+	result.Reset()
+	for decoded, err := range seq {
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		result.WriteString(decoded)
+	}
+	if result.String() != "a" {
+		t.Errorf("Expected %q, got %q", "a", result.String())
+	}
+	if encoding().Name != "x-user-defined" {
+		t.Errorf("Expected x-user-defined, got %v", encoding())
+	}
+
+	// Test invalid label
+	if _, _, err := DecodeSeq(slices.Values([][]byte{}), "invalid", ""); err == nil {
+		t.Error("Expected error for invalid encoding label in DecodeSeq")
+	}
+}
+
+func TestDecodeSeqBreak(t *testing.T) {
+	chunks := [][]byte{[]byte("aaa"), []byte("bbb"), []byte("ccc")}
+	seq, _, err := DecodeSeq(slices.Values(chunks), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeSeq failed: %v", err)
+	}
+
+	count := 0
+	for range seq {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after 1 chunk, got %d", count)
+	}
+}
+
+func TestDecodeReaderSeq(t *testing.T) {
+	input := append([]byte{0xef, 0xbb, 0xbf}, bytes.Repeat([]byte("ab"), readChunkSize)...)
+	seq, encoding, err := DecodeReaderSeq(iotest.OneByteReader(bytes.NewReader(input)), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeReaderSeq failed: %v", err)
+	}
+
+	var result strings.Builder
+	for decoded, err := range seq {
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		result.WriteString(decoded)
+	}
+	if encoding() == nil || encoding().Name != "utf-8" {
+		t.Errorf("Expected utf-8, got %v", encoding())
+	}
+	if result.String() != strings.Repeat("ab", readChunkSize) {
+		t.Errorf("Unexpected decoded output of length %d", result.Len())
+	}
+
+	// Test read errors are reported
+	seq, _, err = DecodeReaderSeq(iotest.ErrReader(iotest.ErrTimeout), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("DecodeReaderSeq failed: %v", err)
+	}
+	for _, err := range seq {
+		if err != iotest.ErrTimeout {
+			t.Errorf("Expected %v, got %v", iotest.ErrTimeout, err)
+		}
+	}
+}
+
+func TestEncodeSeq(t *testing.T) {
+	chunks := []string{"a", "", "", "b"}
+	seq, err := EncodeSeq(slices.Values(chunks), "x-user-defined", "")
+	if err != nil {
+		t.Fatalf("EncodeSeq failed: %v", err)
+	}
+
+	var result []byte
+	for encoded, err := range seq {
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		result = append(result, encoded...)
+	}
+	expected := []byte{'a', 0xf7, 'b'}
+	if !bytes.Equal(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Test unencodable input is reported
+	seq, err = EncodeSeq(slices.Values([]string{"é"}), "x-user-defined", "strict")
+	if err != nil {
+		t.Fatalf("EncodeSeq failed: %v", err)
+	}
+	for _, err := range seq {
+		if err != ErrInvalidRune {
+			t.Errorf("Expected %v, got %v", ErrInvalidRune, err)
+		}
+	}
+
+	// Test invalid label
+	if _, err := EncodeSeq(slices.Values([]string{}), "invalid", ""); err == nil {
+		t.Error("Expected error for invalid encoding label in EncodeSeq")
+	}
+}
@@ -0,0 +1,117 @@
+package webencodings
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF32 is returned when strict decoding meets a UTF-32 code unit that is not a scalar value
+var ErrInvalidUTF32 = errors.New("webencodings: invalid UTF-32")
+
+// utf32LE and utf32BE describe the UTF-32 forms of JSON texts, which the
+// Encoding Standard and so Lookup do not know
+var (
+	utf32LE = &EncodingInfo{Name: "utf-32le"}
+	utf32BE = &EncodingInfo{Name: "utf-32be"}
+)
+
+// DetectJSONEncoding returns the Unicode encoding of a JSON text and the
+// length of its BOM. Without a BOM the encoding is found from the pattern
+// of null bytes in the first four octets, as in RFC 4627: JSON text starts
+// with two ASCII characters. The result is UTF-8, UTF-16BE, UTF-16LE or
+// one of the UTF-32 forms, which are named "utf-32be" and "utf-32le".
+func DetectJSONEncoding(input []byte) (*EncodingInfo, int) {
+	switch name, bomLength, _ := DiagnoseBOM(input); name {
+	case "utf-32le":
+		return utf32LE, bomLength
+	case "utf-32be":
+		return utf32BE, bomLength
+	case "utf-8", "utf-16le", "utf-16be":
+		return Lookup(name), bomLength
+	}
+
+	if len(input) >= 4 {
+		switch {
+		case input[0] == 0 && input[1] == 0 && input[2] == 0 && input[3] != 0:
+			return utf32BE, 0
+		case input[0] != 0 && input[1] == 0 && input[2] == 0 && input[3] == 0:
+			return utf32LE, 0
+		}
+	}
+	if len(input) >= 2 {
+		switch {
+		case input[0] == 0 && input[1] != 0:
+			return utf16BE, 0
+		case input[0] != 0 && input[1] == 0:
+			return utf16LE, 0
+		}
+	}
+	return UTF8, 0
+}
+
+// DecodeJSON decodes a JSON text. RFC 8259 requires UTF-8, which is decoded
+// with the spec's UTF-8 decoder; errors defaults to "strict", so that
+// invalid input fails with ErrInvalidUTF8 rather than being repaired. Texts
+// from older producers in UTF-16 or UTF-32 are decoded too, and the
+// returned encoding tells them apart from UTF-8.
+func DecodeJSON(input []byte, errors string) (string, *EncodingInfo, error) {
+	if errors == "" {
+		errors = "strict"
+	}
+
+	encoding, bomLength := DetectJSONEncoding(input)
+	input = input[bomLength:]
+
+	var decoded []byte
+	var err error
+	switch encoding {
+	case utf32BE, utf32LE:
+		decoded, err = appendDecodeUTF32(make([]byte, 0, len(input)), input, encoding == utf32BE, errors)
+	default:
+		decoded, err = appendDecodeWith(make([]byte, 0, len(input)), input, encoding, errors)
+	}
+	if err != nil {
+		return "", encoding, err
+	}
+	return string(decoded), encoding, nil
+}
+
+// appendDecodeUTF32 decodes UTF-32 input and appends the UTF-8 result to dst
+func appendDecodeUTF32(dst []byte, input []byte, bigEndian bool, errors string) ([]byte, error) {
+	if errors != "strict" && errors != "ignore" && errors != "replace" {
+		return dst, ErrInvalidByte
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+
+	for len(input) > 0 {
+		r := utf8.RuneError
+		valid := false
+		if len(input) >= 4 {
+			unit := order.Uint32(input)
+			if unit <= utf8.MaxRune && !(unit >= 0xD800 && unit <= 0xDFFF) {
+				r, valid = rune(unit), true
+			}
+			input = input[4:]
+		} else {
+			input = nil
+		}
+
+		if valid {
+			dst = utf8.AppendRune(dst, r)
+			continue
+		}
+		switch errors {
+		case "strict":
+			return dst, ErrInvalidUTF32
+		case "replace":
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		}
+	}
+
+	return dst, nil
+}
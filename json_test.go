@@ -0,0 +1,73 @@
+package webencodings
+
+import "testing"
+
+func TestDetectJSONEncoding(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		bomLength int
+	}{
+		{`{"a":1}`, "utf-8", 0},
+		{`1`, "utf-8", 0},
+		{``, "utf-8", 0},
+		{"\xef\xbb\xbf{}", "utf-8", 3},
+		{"\x00{\x00}", "utf-16be", 0},
+		{"{\x00}\x00", "utf-16le", 0},
+		{"\x001", "utf-16be", 0},
+		{"1\x00", "utf-16le", 0},
+		{"\x00\x00\x00{\x00\x00\x00}", "utf-32be", 0},
+		{"{\x00\x00\x00}\x00\x00\x00", "utf-32le", 0},
+		{"\xfe\xff\x00{", "utf-16be", 2},
+		{"\xff\xfe{\x00", "utf-16le", 2},
+		{"\x00\x00\xfe\xff\x00\x00\x00{", "utf-32be", 4},
+		{"\xff\xfe\x00\x00{\x00\x00\x00", "utf-32le", 4},
+		{"+/v8-", "utf-8", 0},
+	}
+
+	for _, test := range tests {
+		encoding, bomLength := DetectJSONEncoding([]byte(test.input))
+		if name := encoding.Name; name != test.expected || bomLength != test.bomLength {
+			t.Errorf("DetectJSONEncoding(%q) = %s, %d, expected %s, %d", test.input, encoding.Name, bomLength, test.expected, test.bomLength)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		name     string
+	}{
+		{`{"a":"é"}`, `{"a":"é"}`, "utf-8"},
+		{"\xef\xbb\xbf[1]", "[1]", "utf-8"},
+		{"[\x00\xe9\x00]\x00", "[é]", "utf-16le"},
+		{"\xfe\xff\x00[\x00]", "[]", "utf-16be"},
+		{"\x00\x00\x00[\x00\x01\xf6\x00\x00\x00\x00]", "[😀]", "utf-32be"},
+		{"[\x00\x00\x00]\x00\x00\x00", "[]", "utf-32le"},
+	}
+
+	for _, test := range tests {
+		decoded, encoding, err := DecodeJSON([]byte(test.input), "")
+		if err != nil {
+			t.Errorf("DecodeJSON(%q) failed: %v", test.input, err)
+			continue
+		}
+		if decoded != test.expected || encoding.Name != test.name {
+			t.Errorf("DecodeJSON(%q) = %q, %s, expected %q, %s", test.input, decoded, encoding.Name, test.expected, test.name)
+		}
+	}
+
+	// Invalid UTF-8 is fatal by default
+	if _, encoding, err := DecodeJSON([]byte("[\"\xe9\"]"), ""); err != ErrInvalidUTF8 || encoding != UTF8 {
+		t.Errorf("Expected %v for utf-8, got %v for %s", ErrInvalidUTF8, err, encoding)
+	}
+	decoded, _, err := DecodeJSON([]byte("[\"\xe9\"]"), "replace")
+	if err != nil || decoded != "[\"�\"]" {
+		t.Errorf("Expected replacement, got %q, %v", decoded, err)
+	}
+
+	if _, _, err := DecodeJSON([]byte("[\x00\x00\x00\x00\xd8\x00\x00"), ""); err != ErrInvalidUTF32 {
+		t.Errorf("Expected %v, got %v", ErrInvalidUTF32, err)
+	}
+}
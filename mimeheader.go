@@ -0,0 +1,512 @@
+package webencodings
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidMediaType is returned by ParseMediaType for a value that does
+// not start with a media type or disposition token
+var ErrInvalidMediaType = errors.New("webencodings: invalid media type")
+
+// maxEncodedWordLen is the longest an RFC 2047 encoded-word may be
+const maxEncodedWordLen = 75
+
+// maxParameterSegmentLen is the longest value FormatMediaType puts in one
+// RFC 2231 parameter continuation, to keep header lines short
+const maxParameterSegmentLen = 60
+
+// NewWordDecoder returns a mime.WordDecoder that decodes words through
+// CharsetReader. Only UTF-8, UTF-16 and x-user-defined have codecs in this
+// package; words in other charsets, such as iso-2022-jp and windows-1251,
+// fail with ErrUnsupportedEncoding.
+func NewWordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{CharsetReader: CharsetReader}
+}
+
+// parseEncodedWord parses the RFC 2047 encoded-word at the start of s,
+// which starts with "=?". It returns the word's encoding, its decoded
+// bytes and its length. ok is false if s does not start with a well-formed
+// word in a charset this package has a codec for. An RFC 2231 language
+// suffix on the charset, as in "=?utf-8*en?q?...?=", is ignored.
+func parseEncodedWord(s string) (encoding *EncodingInfo, raw []byte, n int, ok bool) {
+	charsetEnd := strings.IndexByte(s[2:], '?') + 2
+	if charsetEnd < 3 || len(s) < charsetEnd+3 || s[charsetEnd+2] != '?' {
+		return nil, nil, 0, false
+	}
+	charset, _, _ := strings.Cut(s[2:charsetEnd], "*")
+	scheme := s[charsetEnd+1]
+
+	textStart := charsetEnd + 3
+	textEnd := strings.Index(s[textStart:], "?=")
+	if textEnd < 0 {
+		return nil, nil, 0, false
+	}
+	text := s[textStart : textStart+textEnd]
+	if strings.ContainsAny(text, " \t\r\n") {
+		return nil, nil, 0, false
+	}
+
+	if encoding = Lookup(charset); encoding == nil || !hasCodec(encoding) {
+		return nil, nil, 0, false
+	}
+
+	var err error
+	switch scheme {
+	case 'b', 'B':
+		raw, err = base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			raw, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "="))
+		}
+	case 'q', 'Q':
+		raw, err = decodeQ(text)
+	default:
+		return nil, nil, 0, false
+	}
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	return encoding, raw, textStart + textEnd + 2, true
+}
+
+// decodeQ decodes the "Q" encoding of RFC 2047
+func decodeQ(s string) ([]byte, error) {
+	raw := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '_':
+			raw = append(raw, ' ')
+		case '=':
+			if i+2 >= len(s) {
+				return nil, strconv.ErrSyntax
+			}
+			hi, lo := unhex(s[i+1]), unhex(s[i+2])
+			if hi < 0 || lo < 0 {
+				return nil, strconv.ErrSyntax
+			}
+			raw = append(raw, byte(hi<<4|lo))
+			i += 2
+		default:
+			raw = append(raw, b)
+		}
+	}
+	return raw, nil
+}
+
+// DecodeWords decodes the RFC 2047 encoded-words in a header value, such
+// as "=?utf-8?Q?caf=C3=A9?=", through the codecs of this package.
+// Whitespace between adjacent encoded-words is dropped, and the bytes of
+// adjacent words in the same charset are joined before decoding, so a
+// character split across words survives. Malformed words and words in
+// charsets that are unknown or have no codec, such as windows-1251 and
+// iso-2022-jp, are left as they are. errors defaults to "replace".
+func DecodeWords(header string, errors string) (string, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	var output strings.Builder
+	var pending []byte
+	var pendingEncoding *EncodingInfo
+	flush := func() error {
+		if pendingEncoding == nil {
+			return nil
+		}
+		decoded, err := decodeWith(pending, pendingEncoding, errors)
+		if err != nil {
+			return err
+		}
+		output.WriteString(decoded)
+		pending, pendingEncoding = nil, nil
+		return nil
+	}
+
+	for {
+		start := strings.Index(header, "=?")
+		if start < 0 {
+			break
+		}
+
+		encoding, raw, n, ok := parseEncodedWord(header[start:])
+		if !ok {
+			if err := flush(); err != nil {
+				return "", err
+			}
+			output.WriteString(header[:start+2])
+			header = header[start+2:]
+			continue
+		}
+
+		between := header[:start]
+		header = header[start+n:]
+		if pendingEncoding != nil && strings.Trim(between, " \t\r\n") == "" {
+			if encoding == pendingEncoding {
+				pending = append(pending, raw...)
+				continue
+			}
+		} else {
+			if err := flush(); err != nil {
+				return "", err
+			}
+			output.WriteString(between)
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+		pending, pendingEncoding = raw, encoding
+	}
+
+	if err := flush(); err != nil {
+		return "", err
+	}
+	output.WriteString(header)
+	return output.String(), nil
+}
+
+// isPrintableASCII reports whether s only has printable ASCII characters
+// and tabs
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; (b < ' ' && b != '\t') || b >= 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+// isQSafe reports whether b may appear as it is in a "Q" encoded-word in
+// any part of a header
+func isQSafe(b byte) bool {
+	return isASCIIAlpha(b) || (b >= '0' && b <= '9') || strings.IndexByte("!*+-/", b) >= 0
+}
+
+// EncodeWords encodes text as RFC 2047 encoded-words in encoding, using
+// the "B" or "Q" scheme of wordEncoder. Words are kept within 75
+// characters without splitting characters, and are separated by spaces.
+// Like forms, UTF-16 and replacement are written as UTF-8, and other
+// encodings without a codec fail with ErrUnsupportedEncoding. Text that is
+// printable ASCII and does not look like an encoded-word is returned as it
+// is. errors defaults to "strict".
+func EncodeWords(text string, encoding interface{}, wordEncoder mime.WordEncoder, errors string) (string, error) {
+	if errors == "" {
+		errors = "strict"
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+	enc = OutputEncoding(enc)
+	if !hasCodec(enc) {
+		return "", ErrUnsupportedEncoding
+	}
+	if isPrintableASCII(text) && !strings.Contains(text, "=?") {
+		return text, nil
+	}
+
+	scheme := "b"
+	if wordEncoder == mime.QEncoding {
+		scheme = "q"
+	}
+	prefix := "=?" + enc.Name + "?" + scheme + "?"
+	room := maxEncodedWordLen - len(prefix) - len("?=")
+
+	// encodedLen returns the length of raw in the word's scheme
+	encodedLen := func(raw []byte) int {
+		if scheme == "b" {
+			return base64.StdEncoding.EncodedLen(len(raw))
+		}
+		n := 0
+		for _, b := range raw {
+			if isQSafe(b) || b == ' ' {
+				n++
+			} else {
+				n += 3
+			}
+		}
+		return n
+	}
+
+	var words []string
+	var word, char []byte
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		var encoded string
+		if scheme == "b" {
+			encoded = base64.StdEncoding.EncodeToString(word)
+		} else {
+			var q []byte
+			for _, b := range word {
+				switch {
+				case b == ' ':
+					q = append(q, '_')
+				case isQSafe(b):
+					q = append(q, b)
+				default:
+					q = appendPercentByte(q, b)
+					q[len(q)-3] = '='
+				}
+			}
+			encoded = string(q)
+		}
+		words = append(words, prefix+encoded+"?=")
+		word = word[:0]
+	}
+
+	for len(text) > 0 {
+		_, size := utf8.DecodeRuneInString(text)
+		if char, err = AppendEncode(char[:0], text[:size], enc, errors); err != nil {
+			return "", err
+		}
+		text = text[size:]
+		if len(word) > 0 && encodedLen(append(word, char...)) > room {
+			flush()
+		}
+		word = append(word, char...)
+	}
+	flush()
+	return strings.Join(words, " "), nil
+}
+
+// isAttributeChar reports whether b may appear unescaped in an RFC 2231
+// extended parameter value
+func isAttributeChar(b byte) bool {
+	return b > ' ' && b < 0x7F && strings.IndexByte("*'%()<>@,;:\\\"/[]?=", b) < 0
+}
+
+// splitParameterName splits an RFC 2231 parameter name such as
+// "filename*1*" into its base name, its section number or -1, and whether
+// its value is percent-encoded
+func splitParameterName(name string) (base string, section int, extended bool) {
+	base, rest, found := strings.Cut(name, "*")
+	if !found {
+		return name, -1, false
+	}
+	if rest == "" {
+		return base, -1, true
+	}
+	rest, extended = strings.CutSuffix(rest, "*")
+	section, err := strconv.Atoi(rest)
+	if err != nil || section < 0 || (len(rest) > 1 && rest[0] == '0') {
+		return name, -1, false
+	}
+	return base, section, extended
+}
+
+// decodeParameterSections joins and decodes the sections of an RFC 2231
+// parameter, numbered from 0, where percent-encoded sections start with
+// '*'. ok is false if there is no section 0, or if the charset of the first
+// section is unknown or has no codec.
+func decodeParameterSections(parts map[int]string, errors string) (value string, ok bool, err error) {
+	var charset string
+	var joined []byte
+	for i := 0; ; i++ {
+		part, found := parts[i]
+		if !found {
+			break
+		}
+		if !strings.HasPrefix(part, "*") {
+			joined = append(joined, part...)
+			continue
+		}
+		part = part[1:]
+		if i == 0 {
+			var language string
+			if charset, language, found = strings.Cut(part, "'"); !found {
+				return "", false, nil
+			}
+			if _, part, found = strings.Cut(language, "'"); !found {
+				return "", false, nil
+			}
+		}
+		joined = append(joined, PercentDecodeBytes(part)...)
+	}
+	if _, found := parts[0]; !found {
+		return "", false, nil
+	}
+
+	encoding := UTF8
+	if charset != "" {
+		if encoding = Lookup(charset); encoding == nil || !hasCodec(encoding) {
+			return "", false, nil
+		}
+	}
+	value, err = decodeWith(joined, encoding, errors)
+	return value, err == nil, err
+}
+
+// ParseMediaType parses a Content-Type or Content-Disposition value into
+// its lower cased type and parameters, like mime.ParseMediaType but
+// decoding RFC 2231 parameters, such as filename*=utf-8'en'%C3%A9, in any
+// charset this package has a codec for. Continuations such as title*0*,
+// title*1 are joined before decoding, and win over a single title*, which
+// wins over a plain title. Parameters in charsets that are unknown or have
+// no codec are dropped in favour of the next of these. errors defaults to
+// "replace".
+func ParseMediaType(value string, errors string) (string, map[string]string, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	mediaType, rest, _ := strings.Cut(value, ";")
+	mediaType = ASCIILower(strings.Trim(mediaType, "\t\n\r "))
+	typ, subtype, hasSlash := strings.Cut(mediaType, "/")
+	if !isHTTPToken(typ) || (hasSlash && !isHTTPToken(subtype)) {
+		return "", nil, ErrInvalidMediaType
+	}
+
+	// Collect the raw parameters
+	raw := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, "\t\n\r ;")
+		end := strings.IndexAny(rest, "=;")
+		if end < 0 || rest[end] == ';' {
+			// A parameter without a value
+			if end < 0 {
+				break
+			}
+			rest = rest[end:]
+			continue
+		}
+		name := ASCIILower(strings.Trim(rest[:end], "\t\n\r "))
+		rest = strings.TrimLeft(rest[end+1:], "\t\n\r ")
+
+		var param string
+		if strings.HasPrefix(rest, `"`) {
+			param, rest = collectHTTPQuotedString(rest)
+			_, rest, _ = strings.Cut(rest, ";")
+		} else {
+			param, rest, _ = strings.Cut(rest, ";")
+			param = strings.TrimRight(param, "\t\n\r ")
+		}
+		if _, exists := raw[name]; !exists && isHTTPToken(name) {
+			raw[name] = param
+		}
+	}
+
+	params := make(map[string]string)
+	sections := make(map[string]map[int]string)
+	extended := make(map[string]string)
+	for name, param := range raw {
+		base, section, isExtended := splitParameterName(name)
+		switch {
+		case section >= 0:
+			if sections[base] == nil {
+				sections[base] = make(map[int]string)
+			}
+			if isExtended {
+				// Mark percent-encoded sections; "*" cannot start a value
+				param = "*" + param
+			}
+			sections[base][section] = param
+		case isExtended:
+			extended[base] = "*" + param
+		default:
+			params[base] = param
+		}
+	}
+
+	// Continuations win over a single name*, which wins over a plain name
+	for base, parts := range sections {
+		decoded, ok, err := decodeParameterSections(parts, errors)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			params[base] = decoded
+			delete(extended, base)
+		}
+	}
+	for base, param := range extended {
+		decoded, ok, err := decodeParameterSections(map[int]string{0: param}, errors)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			params[base] = decoded
+		}
+	}
+
+	return mediaType, params, nil
+}
+
+// FormatMediaType serializes a media type or disposition and its
+// parameters, in order of name. Values that are not printable ASCII are
+// written as RFC 2231 extended parameters in encoding, split into numbered
+// continuations when long. UTF-16 and replacement are written as UTF-8, and
+// other encodings without a codec fail with ErrUnsupportedEncoding. errors
+// defaults to "strict".
+func FormatMediaType(mediaType string, params map[string]string, encoding interface{}, errors string) (string, error) {
+	if errors == "" {
+		errors = "strict"
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+	enc = OutputEncoding(enc)
+	if !hasCodec(enc) {
+		return "", ErrUnsupportedEncoding
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(ASCIILower(mediaType))
+	for _, name := range names {
+		value := params[name]
+		b.WriteString("; ")
+
+		if isHTTPToken(value) {
+			b.WriteString(name + "=" + value)
+			continue
+		}
+		if isPrintableASCII(value) {
+			b.WriteString(name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`)
+			continue
+		}
+
+		raw, err := AppendEncode(nil, value, enc, errors)
+		if err != nil {
+			return "", err
+		}
+		var segments []string
+		segment := enc.Name + "''"
+		for _, c := range raw {
+			if len(segment) >= maxParameterSegmentLen {
+				segments = append(segments, segment)
+				segment = ""
+			}
+			if isAttributeChar(c) {
+				segment += string(c)
+			} else {
+				segment = string(appendPercentByte([]byte(segment), c))
+			}
+		}
+		segments = append(segments, segment)
+
+		if len(segments) == 1 {
+			b.WriteString(name + "*=" + segments[0])
+			continue
+		}
+		for i, segment := range segments {
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			b.WriteString(name + "*" + strconv.Itoa(i) + "*=" + segment)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,212 @@
+package webencodings
+
+import (
+	"mime"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"=?utf-8?q?caf=C3=A9?=", "café"},
+		{"=?UTF-8?B?Y2Fmw6k=?=", "café"},
+		{"=?utf-8?b?Y2Fmw6k?=", "café"},
+		{"=?utf-8?q?a_b?= =?utf-8?q?c?=", "a bc"},
+		{"=?utf-8?q?=C3?=\r\n =?utf-8?q?=A9?=", "é"},
+		{"=?utf-8?q?a?= x =?utf-8?q?b?=", "a x b"},
+		{"Re: =?x-user-defined?q?=80?= (x)", "Re: \uf780 (x)"},
+		{"=?utf-8*en?q?hi?=", "hi"},
+		{"=?utf-16le?b?6QA=?==?utf-8?q?x?=", "éx"},
+		{"=?bogus?q?a?= =?utf-8?q?b?=", "=?bogus?q?a?= b"},
+		{"=?utf-8?x?a?=", "=?utf-8?x?a?="},
+		{"=?utf-8?q?a b?=", "=?utf-8?q?a b?="},
+		{"=?utf-8?q?=ZZ?=", "=?utf-8?q?=ZZ?="},
+		{"=?utf-8?q?a", "=?utf-8?q?a"},
+		{"=?utf-8?q?=FF?=", "\uFFFD"},
+
+		// Charsets without a codec stay encoded rather than decode to raw bytes
+		{"=?windows-1251?Q?=CF=F0=E8=E2=E5=F2?=", "=?windows-1251?Q?=CF=F0=E8=E2=E5=F2?="},
+		{"=?iso-2022-jp?B?GyRCJDMkcyRLJEEkTxsoQg==?=", "=?iso-2022-jp?B?GyRCJDMkcyRLJEEkTxsoQg==?="},
+		{"=?utf-8?q?a?= =?windows-1251?q?=CF?=", "a =?windows-1251?q?=CF?="},
+	}
+
+	for _, test := range tests {
+		result, err := DecodeWords(test.input, "")
+		if err != nil || result != test.expected {
+			t.Errorf("DecodeWords(%q): expected %q, got %q (%v)", test.input, test.expected, result, err)
+		}
+	}
+
+	if _, err := DecodeWords("=?utf-8?q?=FF?=", "strict"); err == nil {
+		t.Errorf("Expected an error decoding invalid UTF-8 in strict mode")
+	}
+}
+
+func TestEncodeWords(t *testing.T) {
+	tests := []struct {
+		input       string
+		encoding    string
+		wordEncoder mime.WordEncoder
+		expected    string
+	}{
+		{"plain text", "utf-8", mime.QEncoding, "plain text"},
+		{"café au lait", "utf-8", mime.QEncoding, "=?utf-8?q?caf=C3=A9_au_lait?="},
+		{"café", "utf-8", mime.BEncoding, "=?utf-8?b?Y2Fmw6k=?="},
+		{"é", "utf-16le", mime.BEncoding, "=?utf-8?b?w6k=?="},
+		{"\uf780a", "x-user-defined", mime.QEncoding, "=?x-user-defined?q?=80a?="},
+		{"=?fake?=", "utf-8", mime.QEncoding, "=?utf-8?q?=3D=3Ffake=3F=3D?="},
+	}
+
+	for _, test := range tests {
+		result, err := EncodeWords(test.input, test.encoding, test.wordEncoder, "")
+		if err != nil || result != test.expected {
+			t.Errorf("EncodeWords(%q, %s): expected %q, got %q (%v)", test.input, test.encoding, test.expected, result, err)
+		}
+	}
+
+	if _, err := EncodeWords("é", "x-user-defined", mime.QEncoding, ""); err == nil {
+		t.Errorf("Expected an error encoding an unencodable character in strict mode")
+	}
+	for _, encoding := range []string{"windows-1251", "iso-2022-jp", "shift_jis"} {
+		if _, err := EncodeWords("\u041f", encoding, mime.QEncoding, "strict"); err != ErrUnsupportedEncoding {
+			t.Errorf("EncodeWords in %s: expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+	}
+	if _, err := EncodeWords("a", "bogus", mime.QEncoding, ""); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+}
+
+func TestEncodeWordsRoundTrip(t *testing.T) {
+	input := strings.Repeat("été \U0001F600 ", 20)
+	for _, wordEncoder := range []mime.WordEncoder{mime.BEncoding, mime.QEncoding} {
+		encoded, err := EncodeWords(input, "utf-8", wordEncoder, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, word := range strings.Split(encoded, " ") {
+			if len(word) > 75 {
+				t.Errorf("encoded-word %q is longer than 75 characters", word)
+			}
+		}
+		if decoded, err := DecodeWords(encoded, "strict"); err != nil || decoded != input {
+			t.Errorf("round trip with %c: expected %q, got %q (%v)", wordEncoder, input, decoded, err)
+		}
+		if decoded, err := NewWordDecoder().DecodeHeader(encoded); err != nil || decoded != input {
+			t.Errorf("mime.WordDecoder with %c: expected %q, got %q (%v)", wordEncoder, input, decoded, err)
+		}
+	}
+}
+
+func TestNewWordDecoder(t *testing.T) {
+	decoded, err := NewWordDecoder().DecodeHeader("=?x-user-defined?q?=80?= =?UTF-16BE?b?AOk=?=")
+	if err != nil || decoded != "\uf780é" {
+		t.Errorf("DecodeHeader: got %q (%v)", decoded, err)
+	}
+	if _, err := NewWordDecoder().Decode("=?bogus?q?a?="); err == nil {
+		t.Errorf("Decode: expected an error for an unknown charset")
+	}
+	for _, word := range []string{"=?windows-1251?q?=CF?=", "=?iso-2022-jp?b?GyRCJDMbKEI=?="} {
+		if _, err := NewWordDecoder().Decode(word); err != ErrUnsupportedEncoding {
+			t.Errorf("Decode(%q): expected ErrUnsupportedEncoding, got %v", word, err)
+		}
+	}
+}
+
+func TestParseMediaType(t *testing.T) {
+	tests := []struct {
+		input     string
+		mediaType string
+		params    map[string]string
+	}{
+		{"text/plain", "text/plain", map[string]string{}},
+		{`Text/HTML; Charset="utf-8"`, "text/html", map[string]string{"charset": "utf-8"}},
+		{`attachment; filename="a \"b\".txt"`, "attachment", map[string]string{"filename": `a "b".txt`}},
+		{"attachment; filename*=utf-8''caf%C3%A9.txt", "attachment", map[string]string{"filename": "café.txt"}},
+		{"attachment; filename*=x-user-defined'en'%80", "attachment", map[string]string{"filename": "\uf780"}},
+		{"attachment; filename=a.txt; filename*=utf-8''%C3%A9", "attachment", map[string]string{"filename": "é"}},
+		{"attachment; filename=a.txt; filename*=bogus''%C3%A9", "attachment", map[string]string{"filename": "a.txt"}},
+		{"attachment; title*0*=utf-8''%C3; title*1*=%A9; title*2=\"!\"", "attachment", map[string]string{"title": "é!"}},
+		{"attachment; title*1=b; title*0=a", "attachment", map[string]string{"title": "ab"}},
+		{"attachment; title*0=a; title*2=c", "attachment", map[string]string{"title": "a"}},
+		{"attachment; a=1; flag; b=2;", "attachment", map[string]string{"a": "1", "b": "2"}},
+		{"attachment; filename*=utf-8''%FF", "attachment", map[string]string{"filename": "\uFFFD"}},
+		{"attachment; filename=a.txt; filename*=windows-1251''%CF", "attachment", map[string]string{"filename": "a.txt"}},
+		{"attachment; filename*=utf-8''a; filename*0*=utf-8''b; filename*1=c", "attachment", map[string]string{"filename": "bc"}},
+		{"attachment; filename*0*=utf-8''b; filename*=utf-8''a; filename=d", "attachment", map[string]string{"filename": "b"}},
+		{"attachment; filename*=utf-8''a; filename*0*=bogus''b", "attachment", map[string]string{"filename": "a"}},
+	}
+
+	for _, test := range tests {
+		mediaType, params, err := ParseMediaType(test.input, "")
+		if err != nil || mediaType != test.mediaType || !reflect.DeepEqual(params, test.params) {
+			t.Errorf("ParseMediaType(%q): expected %s %v, got %s %v (%v)", test.input, test.mediaType, test.params, mediaType, params, err)
+		}
+	}
+
+	// Map order must not decide between the forms of a parameter
+	for i := 0; i < 50; i++ {
+		_, params, _ := ParseMediaType("attachment; filename*=utf-8''a; filename*0*=utf-8''b; filename*1=c; filename=d", "")
+		if params["filename"] != "bc" {
+			t.Fatalf("expected the continuations to win, got %q", params["filename"])
+		}
+	}
+
+	for _, input := range []string{"", "; a=b", "text/; a=b", "a b"} {
+		if _, _, err := ParseMediaType(input, ""); err != ErrInvalidMediaType {
+			t.Errorf("ParseMediaType(%q): expected ErrInvalidMediaType, got %v", input, err)
+		}
+	}
+	if _, _, err := ParseMediaType("a; b*=utf-8''%FF", "strict"); err == nil {
+		t.Errorf("Expected an error decoding invalid UTF-8 in strict mode")
+	}
+}
+
+func TestFormatMediaType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		params    map[string]string
+		encoding  string
+		expected  string
+	}{
+		{"Text/Plain", nil, "utf-8", "text/plain"},
+		{"text/plain", map[string]string{"format": "flowed", "charset": "utf-8"}, "utf-8", "text/plain; charset=utf-8; format=flowed"},
+		{"attachment", map[string]string{"filename": `a "b".txt`}, "utf-8", `attachment; filename="a \"b\".txt"`},
+		{"attachment", map[string]string{"filename": "café 1.txt"}, "utf-8", "attachment; filename*=utf-8''caf%C3%A9%201.txt"},
+		{"attachment", map[string]string{"filename": "\uf780"}, "x-user-defined", "attachment; filename*=x-user-defined''%80"},
+		{"attachment", map[string]string{"filename": "é"}, "utf-16be", "attachment; filename*=utf-8''%C3%A9"},
+		{"attachment", map[string]string{"filename": strings.Repeat("é", 20)}, "utf-8",
+			"attachment; filename*0*=utf-8''%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9; " +
+				"filename*1*=%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9%C3%A9; " +
+				"filename*2*=%C3%A9"},
+	}
+
+	for _, test := range tests {
+		result, err := FormatMediaType(test.mediaType, test.params, test.encoding, "")
+		if err != nil || result != test.expected {
+			t.Errorf("FormatMediaType(%s, %v, %s): expected %q, got %q (%v)", test.mediaType, test.params, test.encoding, test.expected, result, err)
+		}
+
+		mediaType, params, err := ParseMediaType(result, "strict")
+		if test.params == nil {
+			test.params = map[string]string{}
+		}
+		if err != nil || mediaType != ASCIILower(test.mediaType) || !reflect.DeepEqual(params, test.params) {
+			t.Errorf("ParseMediaType(%q): expected %v, got %v (%v)", result, test.params, params, err)
+		}
+	}
+
+	for _, encoding := range []string{"windows-1251", "shift_jis"} {
+		if _, err := FormatMediaType("attachment", map[string]string{"filename": "\u041f"}, encoding, ""); err != ErrUnsupportedEncoding {
+			t.Errorf("FormatMediaType in %s: expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+	}
+	if _, err := FormatMediaType("a", map[string]string{"b": "é"}, "x-user-defined", ""); err == nil {
+		t.Errorf("Expected an error encoding an unencodable character in strict mode")
+	}
+}
@@ -0,0 +1,287 @@
+package webencodings
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// ErrInvalidFormData is returned by ParseMultipartForm for a part without a
+// well-formed form-data Content-Disposition
+var ErrInvalidFormData = errors.New("webencodings: invalid multipart/form-data part")
+
+// multipartEscaper applies the escapes the HTML standard requires in the
+// names and filenames of multipart/form-data parts, and nothing else
+var multipartEscaper = strings.NewReplacer("\n", "%0A", "\r", "%0D", `"`, "%22")
+
+// multipartUnescaper reverses multipartEscaper
+var multipartUnescaper = strings.NewReplacer("%0A", "\n", "%0D", "\r", "%22", `"`)
+
+// MultipartFormWriter writes a multipart/form-data body the way HTML form
+// submission does: names, filenames and text values are encoded in the
+// form's encoding, characters it cannot represent become decimal character
+// references, and names and filenames escape LF, CR and '"' as "%0A",
+// "%0D" and "%22".
+type MultipartFormWriter struct {
+	writer   *multipart.Writer
+	encoding *EncodingInfo
+}
+
+// NewMultipartFormWriter returns a writer of a multipart/form-data body to
+// w, in encoding after it has gone through OutputEncoding. An encoding that
+// this package has no codec for, such as shift_jis, fails with
+// ErrUnsupportedEncoding.
+func NewMultipartFormWriter(w io.Writer, encoding interface{}) (*MultipartFormWriter, error) {
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	enc = OutputEncoding(enc)
+	if !hasCodec(enc) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	return &MultipartFormWriter{
+		writer:   multipart.NewWriter(w),
+		encoding: enc,
+	}, nil
+}
+
+// Encoding returns the encoding the form is written in
+func (w *MultipartFormWriter) Encoding() *EncodingInfo {
+	return w.encoding
+}
+
+// Boundary returns the writer's boundary
+func (w *MultipartFormWriter) Boundary() string {
+	return w.writer.Boundary()
+}
+
+// FormDataContentType returns the Content-Type for the body, including the
+// boundary
+func (w *MultipartFormWriter) FormDataContentType() string {
+	return w.writer.FormDataContentType()
+}
+
+// encode encodes s in the form's encoding, writing characters it cannot
+// represent as character references
+func (w *MultipartFormWriter) encode(s string) []byte {
+	// The html error mode cannot fail
+	encoded, _ := AppendEncode(nil, s, w.encoding, "html")
+	return encoded
+}
+
+// disposition returns the Content-Disposition header of a part
+func (w *MultipartFormWriter) disposition(name string, filename *string) string {
+	header := `form-data; name="` + multipartEscaper.Replace(string(w.encode(normalizeNewlines(name)))) + `"`
+	if filename != nil {
+		header += `; filename="` + multipartEscaper.Replace(string(w.encode(*filename))) + `"`
+	}
+	return header
+}
+
+// WriteField writes a text field. The value of a "_charset_" field is
+// replaced by the name of the form's encoding, as in EncodeForm, and line
+// breaks in names and values are normalized to CRLF.
+func (w *MultipartFormWriter) WriteField(name, value string) error {
+	if isFormCharsetField(name) {
+		value = canonicalName(w.encoding)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", w.disposition(name, nil))
+	part, err := w.writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(w.encode(normalizeNewlines(value)))
+	return err
+}
+
+// CreateFormFile starts a file field and returns a writer for its content,
+// which is written as is. contentType defaults to application/octet-stream.
+func (w *MultipartFormWriter) CreateFormFile(name, filename, contentType string) (io.Writer, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", w.disposition(name, &filename))
+	header.Set("Content-Type", contentType)
+	return w.writer.CreatePart(header)
+}
+
+// Close finishes the body by writing the closing boundary
+func (w *MultipartFormWriter) Close() error {
+	return w.writer.Close()
+}
+
+// MultipartFormEntry is a field of a multipart/form-data body
+type MultipartFormEntry struct {
+	// Name is the decoded field name
+	Name string
+	// Value is the decoded text of a field that is not a file
+	Value string
+	// IsFile is set for file fields, which have a Filename, a ContentType
+	// and raw Content instead of a Value
+	IsFile      bool
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// parseFormDataDisposition parses the Content-Disposition of a form-data
+// part. Quoted values are read byte for byte up to the next '"', as
+// browsers do, since the form's encoding may use '\' as a trail byte and
+// '"' is escaped as "%22" instead. ok is false if the disposition is not
+// form-data, has no name, or ends in a quoted value.
+func parseFormDataDisposition(value string) (name, filename string, isFile, ok bool) {
+	disposition, params, _ := strings.Cut(value, ";")
+	if ASCIILower(strings.Trim(disposition, " \t")) != "form-data" {
+		return "", "", false, false
+	}
+
+	hasName := false
+	for {
+		params = strings.TrimLeft(params, " \t;")
+		if params == "" {
+			break
+		}
+		param, rest, found := strings.Cut(params, "=")
+		if !found {
+			break
+		}
+		param = ASCIILower(strings.TrimRight(param, " \t"))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var paramValue string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return "", "", false, false
+			}
+			paramValue, params = rest[1:1+end], rest[2+end:]
+		} else {
+			paramValue, params, _ = strings.Cut(rest, ";")
+			paramValue = strings.TrimRight(paramValue, " \t")
+		}
+
+		switch param {
+		case "name":
+			if !hasName {
+				name, hasName = paramValue, true
+			}
+		case "filename":
+			if !isFile {
+				filename, isFile = paramValue, true
+			}
+		}
+	}
+	return name, filename, isFile, hasName
+}
+
+// ParseMultipartForm reads a multipart/form-data body with the given
+// boundary, decoding names, filenames and text values with encoding. If
+// encoding is nil or "", the value of a "_charset_" field is used when it
+// names a known encoding, and UTF-8 otherwise. The "%0A", "%0D" and "%22"
+// escapes in names and filenames are reversed, so a name that contained
+// "%22" to begin with comes back as '"'. A part without a form-data
+// Content-Disposition and a name fails with ErrInvalidFormData. The whole
+// body is read into memory. It returns the entries and the encoding they
+// were decoded with; errors defaults to "replace". An encoding that this
+// package has no codec for, whether passed in or named by "_charset_",
+// fails with ErrUnsupportedEncoding, though the encoding is still returned.
+func ParseMultipartForm(r io.Reader, boundary string, encoding interface{}, errors string) ([]MultipartFormEntry, *EncodingInfo, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	var enc *EncodingInfo
+	if encoding != nil && encoding != "" {
+		var err error
+		if enc, err = getEncoding(encoding); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Collect the raw parts first, as a _charset_ field may come last
+	type rawEntry struct {
+		name, filename []byte
+		isFile         bool
+		contentType    string
+		content        []byte
+	}
+	var raw []rawEntry
+
+	reader := multipart.NewReader(r, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name, filename, isFile, ok := parseFormDataDisposition(part.Header.Get("Content-Disposition"))
+		if !ok {
+			return nil, nil, ErrInvalidFormData
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entry := rawEntry{
+			name:    []byte(multipartUnescaper.Replace(name)),
+			isFile:  isFile,
+			content: content,
+		}
+		if isFile {
+			entry.filename = []byte(multipartUnescaper.Replace(filename))
+			entry.contentType = part.Header.Get("Content-Type")
+			if entry.contentType == "" {
+				entry.contentType = "text/plain"
+			}
+		}
+		raw = append(raw, entry)
+	}
+
+	if enc == nil {
+		for _, entry := range raw {
+			if !entry.isFile && isFormCharsetField(string(entry.name)) {
+				enc = Lookup(string(entry.content))
+				break
+			}
+		}
+		if enc == nil {
+			enc = UTF8
+		}
+	}
+	if !hasCodec(enc) {
+		return nil, enc, ErrUnsupportedEncoding
+	}
+
+	entries := make([]MultipartFormEntry, 0, len(raw))
+	for _, entry := range raw {
+		name, err := decodeWith(entry.name, enc, errors)
+		if err != nil {
+			return nil, enc, err
+		}
+
+		decoded := MultipartFormEntry{Name: name, IsFile: entry.isFile}
+		if entry.isFile {
+			if decoded.Filename, err = decodeWith(entry.filename, enc, errors); err != nil {
+				return nil, enc, err
+			}
+			decoded.ContentType = entry.contentType
+			decoded.Content = entry.content
+		} else if decoded.Value, err = decodeWith(entry.content, enc, errors); err != nil {
+			return nil, enc, err
+		}
+		entries = append(entries, decoded)
+	}
+	return entries, enc, nil
+}
@@ -0,0 +1,159 @@
+package webencodings
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultipartFormWriter(t *testing.T) {
+	var body bytes.Buffer
+	writer, err := NewMultipartFormWriter(&body, "x-user-defined")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.WriteField("_charset_", "")
+	writer.WriteField("say \"hi\"\n", "é\uf780\nx")
+	file, err := writer.CreateFormFile("upload", "a\"b\r.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("\x00\xff"))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := body.String()
+	for _, expected := range []string{
+		"Content-Disposition: form-data; name=\"_charset_\"\r\n\r\nx-user-defined\r\n",
+		"Content-Disposition: form-data; name=\"say %22hi%22%0D%0A\"\r\n\r\n&#233;\x80\r\nx\r\n",
+		"Content-Disposition: form-data; name=\"upload\"; filename=\"a%22b%0D.txt\"\r\nContent-Type: application/octet-stream\r\n\r\n\x00\xff\r\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected the body to contain %q, got %q", expected, output)
+		}
+	}
+	if !strings.HasSuffix(writer.FormDataContentType(), writer.Boundary()) {
+		t.Errorf("unexpected content type %q", writer.FormDataContentType())
+	}
+
+	entries, encoding, err := ParseMultipartForm(&body, writer.Boundary(), nil, "strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MultipartFormEntry{
+		{Name: "_charset_", Value: "x-user-defined"},
+		{Name: "say \"hi\"\r\n", Value: "&#233;\uf780\r\nx"},
+		{Name: "upload", IsFile: true, Filename: "a\"b\r.txt", ContentType: "application/octet-stream", Content: []byte("\x00\xff")},
+	}
+	if encoding.Name != "x-user-defined" || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ParseMultipartForm: expected %+v, got %+v in %s", expected, entries, encoding.Name)
+	}
+}
+
+func TestMultipartFormWriterOutputEncoding(t *testing.T) {
+	var body bytes.Buffer
+	writer, _ := NewMultipartFormWriter(&body, "utf-16le")
+	if writer.Encoding() != UTF8 {
+		t.Errorf("expected UTF-16 forms to be written as UTF-8, got %v", writer.Encoding())
+	}
+	writer.WriteField("_charset_", "replaced")
+	writer.Close()
+	if expected := "name=\"_charset_\"\r\n\r\nUTF-8\r\n"; !strings.Contains(body.String(), expected) {
+		t.Errorf("expected the body to contain %q, got %q", expected, body.String())
+	}
+
+	if _, err := NewMultipartFormWriter(&body, "bogus"); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+	if _, err := NewMultipartFormWriter(&body, "shift_jis"); err != ErrUnsupportedEncoding {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestParseMultipartForm(t *testing.T) {
+	body := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"q\"\r\n\r\n" +
+		"\x80\r\n" +
+		"--b\r\n" +
+		"Content-Disposition: form-data; name=\"f\"; filename=\"\x80.txt\"\r\n\r\n" +
+		"data\r\n" +
+		"--b--\r\n"
+
+	tests := []struct {
+		encoding interface{}
+		expected []MultipartFormEntry
+	}{
+		{"x-user-defined", []MultipartFormEntry{
+			{Name: "q", Value: "\uf780"},
+			{Name: "f", IsFile: true, Filename: "\uf780.txt", ContentType: "text/plain", Content: []byte("data")},
+		}},
+		{nil, []MultipartFormEntry{
+			{Name: "q", Value: "\uFFFD"},
+			{Name: "f", IsFile: true, Filename: "\uFFFD.txt", ContentType: "text/plain", Content: []byte("data")},
+		}},
+	}
+
+	for _, test := range tests {
+		entries, _, err := ParseMultipartForm(strings.NewReader(body), "b", test.encoding, "")
+		if err != nil {
+			t.Errorf("ParseMultipartForm in %v: unexpected error %v", test.encoding, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, test.expected) {
+			t.Errorf("ParseMultipartForm in %v: expected %+v, got %+v", test.encoding, test.expected, entries)
+		}
+	}
+
+	if _, _, err := ParseMultipartForm(strings.NewReader(body), "b", "utf-8", "strict"); err == nil {
+		t.Errorf("Expected an error parsing invalid UTF-8 in strict mode")
+	}
+
+	// Encodings without a codec are refused, even when named by _charset_
+	charsetBody := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"_charset_\"\r\n\r\n" +
+		"Shift_JIS\r\n" +
+		body
+	for _, test := range []struct {
+		body     string
+		encoding interface{}
+	}{
+		{body, "shift_jis"},
+		{charsetBody, nil},
+	} {
+		entries, encoding, err := ParseMultipartForm(strings.NewReader(test.body), "b", test.encoding, "")
+		if err != ErrUnsupportedEncoding || entries != nil || encoding == nil || encoding.Name != "shift_jis" {
+			t.Errorf("ParseMultipartForm in %v: expected ErrUnsupportedEncoding in shift_jis, got %+v in %v (%v)", test.encoding, entries, encoding, err)
+		}
+	}
+}
+
+func TestParseMultipartFormTrailBytes(t *testing.T) {
+	// Shift_JIS and Big5 use 0x5C as a trail byte, which is not an escape
+	body := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"\x83\x5c\"; filename=\"\x95\x5c.txt\"\r\n\r\n" +
+		"x\r\n" +
+		"--b--\r\n"
+	entries, _, err := ParseMultipartForm(strings.NewReader(body), "b", "x-user-defined", "")
+	expected := []MultipartFormEntry{
+		{Name: "\uf783\\", IsFile: true, Filename: "\uf795\\.txt", ContentType: "text/plain", Content: []byte("x")},
+	}
+	if err != nil || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %+v, got %+v (%v)", expected, entries, err)
+	}
+}
+
+func TestParseMultipartFormInvalid(t *testing.T) {
+	for _, disposition := range []string{
+		"",
+		`attachment; name="x"`,
+		`form-data; filename="x"`,
+		`form-data; name="x`,
+	} {
+		body := "--b\r\nContent-Disposition: " + disposition + "\r\n\r\nx\r\n--b--\r\n"
+		if _, _, err := ParseMultipartForm(strings.NewReader(body), "b", "utf-8", ""); err != ErrInvalidFormData {
+			t.Errorf("Content-Disposition %q: expected ErrInvalidFormData, got %v", disposition, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package webencodings
+
+import "strconv"
+
+// PercentEncodeSet is a set of bytes to percent-encode, as defined by the
+// URL Standard. C0 controls and all bytes above U+007E are in every set.
+type PercentEncodeSet struct {
+	ascii [128]bool
+}
+
+// newPercentEncodeSet returns a copy of base, or of the C0 control set if
+// base is nil, with chars added
+func newPercentEncodeSet(base *PercentEncodeSet, chars string) *PercentEncodeSet {
+	set := &PercentEncodeSet{}
+	if base != nil {
+		*set = *base
+	} else {
+		for b := 0; b < 0x20; b++ {
+			set.ascii[b] = true
+		}
+		set.ascii[0x7F] = true
+	}
+	for i := 0; i < len(chars); i++ {
+		set.ascii[chars[i]] = true
+	}
+	return set
+}
+
+// The percent-encode sets of the URL Standard. Each includes the ones
+// before it, except that SpecialQuery and Path both extend Query.
+var (
+	C0ControlPercentEncodeSet      = newPercentEncodeSet(nil, "")
+	FragmentPercentEncodeSet       = newPercentEncodeSet(C0ControlPercentEncodeSet, " \"<>`")
+	QueryPercentEncodeSet          = newPercentEncodeSet(C0ControlPercentEncodeSet, " \"#<>")
+	SpecialQueryPercentEncodeSet   = newPercentEncodeSet(QueryPercentEncodeSet, "'")
+	PathPercentEncodeSet           = newPercentEncodeSet(QueryPercentEncodeSet, "?^`{}")
+	UserinfoPercentEncodeSet       = newPercentEncodeSet(PathPercentEncodeSet, "/:;=@[\\]|")
+	ComponentPercentEncodeSet      = newPercentEncodeSet(UserinfoPercentEncodeSet, "$%&+,")
+	FormURLEncodedPercentEncodeSet = newPercentEncodeSet(ComponentPercentEncodeSet, "!'()~")
+)
+
+// With returns a copy of s that also contains the ASCII bytes of chars
+func (s *PercentEncodeSet) With(chars string) *PercentEncodeSet {
+	return newPercentEncodeSet(s, chars)
+}
+
+// Contains reports whether b is in the set
+func (s *PercentEncodeSet) Contains(b byte) bool {
+	return b >= 0x80 || s.ascii[b]
+}
+
+// PercentEncode implements the URL Standard's "percent-encode after
+// encoding": input is encoded with encoding, and the bytes in set are
+// percent-encoded. Characters the encoding cannot represent become
+// "%26%23NNN%3B", a percent-encoded decimal character reference, as in the
+// html error mode of Encode. If spaceAsPlus is true, spaces become "+".
+//
+// encoding is passed through OutputEncoding, so UTF-16 pages encode their
+// URLs as UTF-8 like browsers do. An encoding that this package has no
+// codec for, such as shift_jis, fails with ErrUnsupportedEncoding.
+func PercentEncode(input string, encoding interface{}, set *PercentEncodeSet, spaceAsPlus bool) (string, error) {
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+	enc = OutputEncoding(enc)
+	if !hasCodec(enc) {
+		return "", ErrUnsupportedEncoding
+	}
+
+	return string(appendPercentEncode(make([]byte, 0, len(input)), input, enc, set, spaceAsPlus)), nil
+}
+
+// appendPercentEncode implements PercentEncode, appending to dst. encoding
+// must have a codec.
+func appendPercentEncode(dst []byte, input string, encoding *EncodingInfo, set *PercentEncodeSet, spaceAsPlus bool) []byte {
+	var encoded []byte
+	for {
+		var r rune
+		encoded, r, input = encodeOrFail(encoded[:0], input, encoding)
+		for _, b := range encoded {
+			switch {
+			case spaceAsPlus && b == ' ':
+				dst = append(dst, '+')
+			case set.Contains(b):
+				dst = appendPercentByte(dst, b)
+			default:
+				dst = append(dst, b)
+			}
+		}
+		if r < 0 {
+			return dst
+		}
+
+		dst = append(dst, "%26%23"...)
+		dst = strconv.AppendInt(dst, int64(r), 10)
+		dst = append(dst, "%3B"...)
+	}
+}
+
+// appendPercentByte appends b as "%" and two upper case hex digits
+func appendPercentByte(dst []byte, b byte) []byte {
+	const hex = "0123456789ABCDEF"
+	return append(dst, '%', hex[b>>4], hex[b&0xF])
+}
+
+// unhex returns the value of the hex digit b, or -1
+func unhex(b byte) int {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0')
+	case b >= 'a' && b <= 'f':
+		return int(b-'a') + 10
+	case b >= 'A' && b <= 'F':
+		return int(b-'A') + 10
+	}
+	return -1
+}
+
+// PercentDecodeBytes implements the URL Standard's "percent-decode". A "%"
+// that is not followed by two hex digits is kept as it is.
+func PercentDecodeBytes(input string) []byte {
+	output := make([]byte, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		if input[i] == '%' && i+2 < len(input) {
+			if hi, lo := unhex(input[i+1]), unhex(input[i+2]); hi >= 0 && lo >= 0 {
+				output = append(output, byte(hi<<4|lo))
+				i += 2
+				continue
+			}
+		}
+		output = append(output, input[i])
+	}
+	return output
+}
+
+// PercentDecode percent-decodes input and decodes the resulting bytes with
+// encoding, without sniffing a BOM. errors defaults to "replace". An
+// encoding that this package has no codec for fails with
+// ErrUnsupportedEncoding.
+func PercentDecode(input string, encoding interface{}, errors string) (string, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+	if !hasCodec(enc) {
+		return "", ErrUnsupportedEncoding
+	}
+
+	return decodeWith(PercentDecodeBytes(input), enc, errors)
+}
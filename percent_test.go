@@ -0,0 +1,98 @@
+package webencodings
+
+import "testing"
+
+func TestPercentEncode(t *testing.T) {
+	tests := []struct {
+		input       string
+		encoding    string
+		set         *PercentEncodeSet
+		spaceAsPlus bool
+		expected    string
+	}{
+		{"a b", "utf-8", QueryPercentEncodeSet, false, "a%20b"},
+		{"a b", "utf-8", FormURLEncodedPercentEncodeSet, true, "a+b"},
+		{"é", "utf-8", C0ControlPercentEncodeSet, false, "%C3%A9"},
+		{"é", "utf-16le", QueryPercentEncodeSet, false, "%C3%A9"},
+		{"é", "x-user-defined", QueryPercentEncodeSet, false, "%26%23233%3B%80"},
+		{"a&b=c", "x-user-defined", QueryPercentEncodeSet, false, "a&b=c"},
+		{"a&b=c", "x-user-defined", ComponentPercentEncodeSet, false, "a%26b%3Dc"},
+		{"\x00\x1f\x7f", "utf-8", C0ControlPercentEncodeSet, false, "%00%1F%7F"},
+		{"'", "utf-8", QueryPercentEncodeSet, false, "'"},
+		{"'", "utf-8", SpecialQueryPercentEncodeSet, false, "%27"},
+		{"#`", "utf-8", FragmentPercentEncodeSet, false, "#%60"},
+		{"?^{}", "utf-8", PathPercentEncodeSet, false, "%3F%5E%7B%7D"},
+		{"/:[|]", "utf-8", UserinfoPercentEncodeSet, false, "%2F%3A%5B%7C%5D"},
+		{"!~*-._", "utf-8", FormURLEncodedPercentEncodeSet, false, "%21%7E*-._"},
+	}
+
+	for _, test := range tests {
+		result, err := PercentEncode(test.input, test.encoding, test.set, test.spaceAsPlus)
+		if err != nil {
+			t.Errorf("PercentEncode(%q, %s): unexpected error %v", test.input, test.encoding, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("PercentEncode(%q, %s): expected %q, got %q", test.input, test.encoding, test.expected, result)
+		}
+	}
+
+	if _, err := PercentEncode("a", "bogus", QueryPercentEncodeSet, false); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+	for _, encoding := range []string{"shift_jis", "windows-1252"} {
+		if _, err := PercentEncode("\u00e9", encoding, QueryPercentEncodeSet, false); err != ErrUnsupportedEncoding {
+			t.Errorf("PercentEncode(%s): expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+	}
+}
+
+func TestPercentEncodeSetWith(t *testing.T) {
+	set := QueryPercentEncodeSet.With("&=")
+	if !set.Contains('&') || !set.Contains(' ') || !set.Contains(0x80) || set.Contains('a') {
+		t.Errorf("unexpected contents of the extended set")
+	}
+	if QueryPercentEncodeSet.Contains('&') {
+		t.Errorf("With modified the original set")
+	}
+}
+
+func TestPercentDecode(t *testing.T) {
+	tests := []struct {
+		input    string
+		encoding string
+		expected string
+	}{
+		{"a%20b", "utf-8", "a b"},
+		{"%C3%A9", "utf-8", "é"},
+		{"%c3%a9", "utf-8", "é"},
+		{"%FF", "utf-8", "\uFFFD"},
+		{"%80", "x-user-defined", "\uf780"},
+		{"%EF%BB%BFa", "utf-8", "\uFEFFa"},
+		{"100%", "utf-8", "100%"},
+		{"%2", "utf-8", "%2"},
+		{"%zz%41", "utf-8", "%zzA"},
+		{"a+b", "utf-8", "a+b"},
+	}
+
+	for _, test := range tests {
+		result, err := PercentDecode(test.input, test.encoding, "")
+		if err != nil {
+			t.Errorf("PercentDecode(%q, %s): unexpected error %v", test.input, test.encoding, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("PercentDecode(%q, %s): expected %q, got %q", test.input, test.encoding, test.expected, result)
+		}
+	}
+
+	if _, err := PercentDecode("%FF", "utf-8", "strict"); err == nil {
+		t.Errorf("Expected an error decoding invalid UTF-8 in strict mode")
+	}
+	if _, err := PercentDecode("%82%A0", "shift_jis", ""); err != ErrUnsupportedEncoding {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+	if _, err := PercentDecode("a", "bogus", ""); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package webencodings
+
+import "io"
+
+// decodeReader decodes the bytes of an io.Reader with an incremental
+// decode function and serves the UTF-8 result
+type decodeReader struct {
+	r      io.Reader
+	decode func([]byte, bool) (string, error)
+	buf    []byte
+	out    []byte
+	err    error
+}
+
+// newDecodeReader returns a reader that decodes r with decode
+func newDecodeReader(r io.Reader, decode func([]byte, bool) (string, error)) *decodeReader {
+	return &decodeReader{
+		r:      r,
+		decode: decode,
+		buf:    make([]byte, readChunkSize),
+	}
+}
+
+// Read reads decoded UTF-8 text into p
+func (dr *decodeReader) Read(p []byte) (int, error) {
+	for len(dr.out) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+
+		// Bytes read alongside an error are decoded before the error is
+		// passed on, as io.Reader requires
+		n, err := dr.r.Read(dr.buf)
+		decoded, decodeErr := dr.decode(dr.buf[:n], err == io.EOF)
+		if decodeErr != nil {
+			dr.err = decodeErr
+			continue
+		}
+		dr.out = append(dr.out[:0], decoded...)
+		dr.err = err
+	}
+
+	n := copy(p, dr.out)
+	dr.out = dr.out[n:]
+	return n, nil
+}
@@ -0,0 +1,52 @@
+package webencodings
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeReader(t *testing.T) {
+	input := bytes.Repeat([]byte{'a', 0xf7}, readChunkSize)
+	expected := strings.Repeat("a\uf7f7", readChunkSize)
+
+	reader := newDecodeReader(bytes.NewReader(input), newChunkDecoder(Lookup("x-user-defined"), ""))
+	if err := iotest.TestReader(reader, []byte(expected)); err != nil {
+		t.Error(err)
+	}
+
+	// Read errors are passed on once decoded text has been served
+	reader = newDecodeReader(iotest.TimeoutReader(bytes.NewReader(input)), newChunkDecoder(Lookup("x-user-defined"), ""))
+	result, err := io.ReadAll(reader)
+	if err != iotest.ErrTimeout {
+		t.Errorf("Expected %v, got %v", iotest.ErrTimeout, err)
+	}
+	if string(result) != strings.Repeat("a\uf7f7", readChunkSize/2) {
+		t.Errorf("Unexpected output of length %d before the error", len(result))
+	}
+
+	// Data returned together with an error is decoded before the error
+	reader = newDecodeReader(&dataErrReader{[]byte{'a', 0xf7}, iotest.ErrTimeout}, newChunkDecoder(Lookup("x-user-defined"), ""))
+	result, err = io.ReadAll(reader)
+	if err != iotest.ErrTimeout {
+		t.Errorf("Expected %v, got %v", iotest.ErrTimeout, err)
+	}
+	if string(result) != "a\uf7f7" {
+		t.Errorf("Expected %q, got %q", "a\uf7f7", result)
+	}
+}
+
+// dataErrReader returns all of its data together with err on the first
+// read, and err on every read after that
+type dataErrReader struct {
+	data []byte
+	err  error
+}
+
+func (r *dataErrReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, r.err
+}
@@ -0,0 +1,94 @@
+package webencodings
+
+// Source is the step of encoding determination that chose an encoding
+type Source int
+
+const (
+	// SourceFallback is the caller's fallback or the format's default encoding
+	SourceFallback Source = iota
+	// SourceBOM is a byte order mark at the start of the input
+	SourceBOM
+	// SourceTransport is the transport layer, such as a Content-Type charset
+	SourceTransport
+	// SourcePrescan is a <meta> tag found by PrescanHTML
+	SourcePrescan
+	// SourceXMLDeclaration is an XML declaration or its UTF-16 byte pattern
+	SourceXMLDeclaration
+	// SourceCSSCharset is a CSS @charset rule
+	SourceCSSCharset
+	// SourceDetector is the best guess of DetectEncoding
+	SourceDetector
+)
+
+// sourceNames are the String values of each Source
+var sourceNames = [...]string{
+	SourceFallback:       "fallback",
+	SourceBOM:            "bom",
+	SourceTransport:      "transport",
+	SourcePrescan:        "prescan",
+	SourceXMLDeclaration: "xml-declaration",
+	SourceCSSCharset:     "css-charset",
+	SourceDetector:       "detector",
+}
+
+// String returns a short lower case name for the source
+func (s Source) String() string {
+	if s < 0 || int(s) >= len(sourceNames) {
+		return "unknown"
+	}
+	return sourceNames[s]
+}
+
+// Confidence returns how sure an encoding from s is. A BOM, the transport
+// layer and in-document declarations of XML and CSS are final; the HTML
+// prescan, the detector and fallbacks are only tentative.
+func (s Source) Confidence() Confidence {
+	switch s {
+	case SourceBOM, SourceTransport, SourceXMLDeclaration, SourceCSSCharset:
+		return Certain
+	}
+	return Tentative
+}
+
+// DecodeResult records which encoding a decode used and why
+type DecodeResult struct {
+	// Encoding is the encoding the input was decoded with
+	Encoding *EncodingInfo
+	// Source is the step that chose Encoding
+	Source Source
+	// Confidence is how sure that step is of Encoding
+	Confidence Confidence
+	// BOMLength is the number of BOM bytes stripped from the input
+	BOMLength int
+}
+
+// newDecodeResult returns the result for an encoding chosen by source
+func newDecodeResult(encoding *EncodingInfo, source Source) *DecodeResult {
+	return &DecodeResult{
+		Encoding:   encoding,
+		Source:     source,
+		Confidence: source.Confidence(),
+	}
+}
+
+// decodeWithResult decodes input with the encoding of result, failing with
+// ErrUnsupportedEncoding, but still returning result, if that encoding has
+// no codec in this package
+func decodeWithResult(input []byte, result *DecodeResult, errors string) (string, *DecodeResult, error) {
+	if !hasCodec(result.Encoding) {
+		return "", result, ErrUnsupportedEncoding
+	}
+	decoded, err := decodeWith(input, result.Encoding, errors)
+	return decoded, result, err
+}
+
+// sniffBOM is DetectBOM returning a DecodeResult, or nil if there is no BOM
+func sniffBOM(input []byte) (*DecodeResult, []byte) {
+	encoding, remaining := DetectBOM(input)
+	if encoding == nil {
+		return nil, input
+	}
+	result := newDecodeResult(encoding, SourceBOM)
+	result.BOMLength = len(input) - len(remaining)
+	return result, remaining
+}
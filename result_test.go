@@ -0,0 +1,138 @@
+package webencodings
+
+import "testing"
+
+func TestDecodeWithResult(t *testing.T) {
+	tests := []struct {
+		input      []byte
+		encoding   string
+		source     Source
+		confidence Confidence
+		bomLength  int
+	}{
+		{[]byte("\xEF\xBB\xBFabc"), "utf-8", SourceBOM, Certain, 3},
+		{[]byte("\xFF\xFEa\x00"), "utf-16le", SourceBOM, Certain, 2},
+		{[]byte("\xFE\xFF\x00a"), "utf-16be", SourceBOM, Certain, 2},
+		{[]byte("abc"), "windows-1252", SourceFallback, Tentative, 0},
+	}
+
+	for _, test := range tests {
+		_, result, err := DecodeWithResult(test.input, "latin1", "")
+		if err != nil {
+			t.Errorf("DecodeWithResult(%q): unexpected error %v", test.input, err)
+			continue
+		}
+		expected := DecodeResult{Lookup(test.encoding), test.source, test.confidence, test.bomLength}
+		if *result != expected {
+			t.Errorf("DecodeWithResult(%q): expected %+v, got %+v", test.input, expected, *result)
+		}
+	}
+
+	if _, result, err := DecodeWithResult([]byte("abc"), "unknown", ""); err != ErrUnknownEncoding || result != nil {
+		t.Errorf("DecodeWithResult with unknown fallback: expected nil and ErrUnknownEncoding, got %v and %v", result, err)
+	}
+}
+
+func TestDecodeHTMLWithResult(t *testing.T) {
+	tests := []struct {
+		input     string
+		transport interface{}
+		encoding  string
+		source    Source
+		err       error
+	}{
+		{"\xEF\xBB\xBF<meta charset=koi8-r>", "iso-8859-2", "utf-8", SourceBOM, nil},
+		{"<meta charset=koi8-r>", "iso-8859-2", "iso-8859-2", SourceTransport, ErrUnsupportedEncoding},
+		{"<meta charset=koi8-r>", "utf-16be", "utf-16be", SourceTransport, nil},
+		{"<meta charset=koi8-r>", "bogus", "koi8-r", SourcePrescan, ErrUnsupportedEncoding},
+		{"<meta charset=koi8-r>", nil, "koi8-r", SourcePrescan, ErrUnsupportedEncoding},
+		{"<meta charset=utf-8>", nil, "utf-8", SourcePrescan, nil},
+		{"<p>no declaration", "", "windows-1252", SourceFallback, ErrUnsupportedEncoding},
+		{"<p>no declaration", "", "x-user-defined", SourceFallback, nil},
+	}
+
+	for _, test := range tests {
+		fallback := "windows-1252"
+		if test.source == SourceFallback {
+			fallback = test.encoding
+		}
+		decoded, result, err := DecodeHTMLWithResult([]byte(test.input), test.transport, fallback, "")
+		if err != test.err {
+			t.Errorf("DecodeHTMLWithResult(%q): expected error %v, got %v", test.input, test.err, err)
+		}
+		if result == nil {
+			t.Errorf("DecodeHTMLWithResult(%q): expected a result", test.input)
+			continue
+		}
+		if result.Encoding.Name != test.encoding || result.Source != test.source || result.Confidence != test.source.Confidence() {
+			t.Errorf("DecodeHTMLWithResult(%q): expected %s from %s, got %+v", test.input, test.encoding, test.source, *result)
+		}
+		plain, encoding, _ := DecodeHTML([]byte(test.input), test.transport, fallback, "")
+		if plain != decoded || encoding != result.Encoding {
+			t.Errorf("DecodeHTML(%q) disagrees with DecodeHTMLWithResult", test.input)
+		}
+	}
+}
+
+func TestDecodeXMLWithResult(t *testing.T) {
+	tests := []struct {
+		input     string
+		encoding  string
+		source    Source
+		bomLength int
+		err       error
+	}{
+		{"\xEF\xBB\xBF<?xml version=\"1.0\"?><a/>", "utf-8", SourceBOM, 3, nil},
+		{"<?xml version=\"1.0\" encoding=\"x-user-defined\"?><a/>", "x-user-defined", SourceXMLDeclaration, 0, nil},
+		{"<?xml version=\"1.0\" encoding=\"windows-1251\"?><a/>", "windows-1251", SourceXMLDeclaration, 0, ErrUnsupportedEncoding},
+		{"<\x00?\x00x\x00m\x00l\x00", "utf-16le", SourceXMLDeclaration, 0, nil},
+		{"<a/>", "utf-8", SourceFallback, 0, nil},
+	}
+
+	for _, test := range tests {
+		_, result, err := DecodeXMLWithResult([]byte(test.input), "")
+		if err != test.err {
+			t.Errorf("DecodeXMLWithResult(%q): expected error %v, got %v", test.input, test.err, err)
+		}
+		if result.Encoding.Name != test.encoding || result.Source != test.source || result.BOMLength != test.bomLength {
+			t.Errorf("DecodeXMLWithResult(%q): expected %s from %s, got %+v", test.input, test.encoding, test.source, *result)
+		}
+	}
+}
+
+func TestDecodeCSSWithResult(t *testing.T) {
+	tests := []struct {
+		input       string
+		protocol    interface{}
+		environment interface{}
+		encoding    string
+		source      Source
+		err         error
+	}{
+		{"\xFE\xFF\x00a", "koi8-r", nil, "utf-16be", SourceBOM, nil},
+		{"@charset \"koi8-r\";", "iso-8859-2", nil, "iso-8859-2", SourceTransport, ErrUnsupportedEncoding},
+		{"@charset \"koi8-r\";", nil, "iso-8859-2", "koi8-r", SourceCSSCharset, ErrUnsupportedEncoding},
+		{"a{}", nil, "iso-8859-2", "iso-8859-2", SourceFallback, ErrUnsupportedEncoding},
+		{"a{}", nil, "x-user-defined", "x-user-defined", SourceFallback, nil},
+		{"a{}", nil, nil, "utf-8", SourceFallback, nil},
+	}
+
+	for _, test := range tests {
+		_, result, err := DecodeCSSWithResult([]byte(test.input), test.protocol, test.environment, "")
+		if err != test.err {
+			t.Errorf("DecodeCSSWithResult(%q): expected error %v, got %v", test.input, test.err, err)
+		}
+		if result.Encoding.Name != test.encoding || result.Source != test.source {
+			t.Errorf("DecodeCSSWithResult(%q): expected %s from %s, got %+v", test.input, test.encoding, test.source, *result)
+		}
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	if SourceDetector.String() != "detector" || SourceDetector.Confidence() != Tentative {
+		t.Errorf("unexpected Source String or Confidence")
+	}
+	if Source(-1).String() != "unknown" || Source(100).String() != "unknown" {
+		t.Errorf("expected out of range sources to be unknown")
+	}
+}
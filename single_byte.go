@@ -0,0 +1,62 @@
+package webencodings
+
+// byteRange maps a contiguous run of code points onto consecutive bytes
+type byteRange struct {
+	first rune
+	last  rune
+	base  byte
+}
+
+// singleByteIndex is a compact, sorted reverse index of a single-byte
+// decoding table. Legacy single-byte encodings map long runs of code points
+// onto consecutive bytes, so a handful of ranges replaces a 256-entry map.
+type singleByteIndex []byteRange
+
+// newSingleByteIndex builds the reverse index of table
+func newSingleByteIndex(table *[256]rune) singleByteIndex {
+	var pairs [256]byteRange
+	for i, r := range table {
+		pairs[i] = byteRange{first: r, last: r, base: byte(i)}
+	}
+
+	// Sort by code point; insertion sort is plenty for 256 entries
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].first < pairs[j-1].first; j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+
+	var index singleByteIndex
+	for _, p := range pairs {
+		if n := len(index); n > 0 {
+			last := &index[n-1]
+			if p.first == last.last {
+				// Several bytes decode to the same code point: keep the first
+				continue
+			}
+			if p.first == last.last+1 && int(p.base) == int(last.base)+int(last.last-last.first)+1 {
+				last.last = p.first
+				continue
+			}
+		}
+		index = append(index, p)
+	}
+	return index
+}
+
+// lookup returns the byte that encodes r, if any
+func (index singleByteIndex) lookup(r rune) (byte, bool) {
+	lo, hi := 0, len(index)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		switch rng := index[mid]; {
+		case r < rng.first:
+			hi = mid
+		case r > rng.last:
+			lo = mid + 1
+		default:
+			return rng.base + byte(r-rng.first), true
+		}
+	}
+	return 0, false
+}
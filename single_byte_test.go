@@ -0,0 +1,57 @@
+package webencodings
+
+import "testing"
+
+func TestSingleByteIndex(t *testing.T) {
+	// x-user-defined collapses into two ranges: ASCII and U+F780..U+F7FF
+	if len(xUserDefinedIndex) != 2 {
+		t.Errorf("Expected 2 ranges for x-user-defined, got %d", len(xUserDefinedIndex))
+	}
+
+	for i, r := range DecodingTable {
+		b, ok := xUserDefinedIndex.lookup(r)
+		if !ok || b != byte(i) {
+			t.Errorf("lookup(%U) = %#x, %v, expected %#x", r, b, ok, i)
+		}
+	}
+
+	for _, r := range []rune{0x80, 0xe9, 0xf77f, 0xf800, 0x10ffff, -1} {
+		if b, ok := xUserDefinedIndex.lookup(r); ok {
+			t.Errorf("lookup(%U) = %#x, expected no mapping", r, b)
+		}
+	}
+}
+
+func TestNewSingleByteIndex(t *testing.T) {
+	var table [256]rune
+	for i := range table {
+		table[i] = rune(i)
+	}
+	// Scatter a few code points, and map two bytes to the same code point
+	table[0xa4] = 0x20ac
+	table[0xa5] = 0x0410
+	table[0xa6] = 0x0410
+
+	index := newSingleByteIndex(&table)
+	tests := []struct {
+		r        rune
+		expected byte
+		ok       bool
+	}{
+		{'a', 'a', true},
+		{0xa3, 0xa3, true},
+		{0xa4, 0, false},
+		{0x20ac, 0xa4, true},
+		{0x0410, 0xa5, true},
+		{0xa7, 0xa7, true},
+		{0xff, 0xff, true},
+		{0x100, 0, false},
+	}
+
+	for _, test := range tests {
+		b, ok := index.lookup(test.r)
+		if b != test.expected || ok != test.ok {
+			t.Errorf("lookup(%U) = %#x, %v, expected %#x, %v", test.r, b, ok, test.expected, test.ok)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package webencodings
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// transformStream passes the bytes written to it through transform and
+// serves the result to readers over an io.Pipe
+type transformStream struct {
+	reader    *io.PipeReader
+	writer    *io.PipeWriter
+	transform func([]byte, bool) ([]byte, error)
+	// closed is set by Close and CloseWithError, which may be called from
+	// the reading side while a write blocks
+	closed atomic.Bool
+}
+
+// newTransformStream returns a stream that applies transform
+func newTransformStream(transform func([]byte, bool) ([]byte, error)) *transformStream {
+	reader, writer := io.Pipe()
+	return &transformStream{
+		reader:    reader,
+		writer:    writer,
+		transform: transform,
+	}
+}
+
+// write transforms p and blocks until readers have taken the result
+func (s *transformStream) write(p []byte, final bool) error {
+	if final {
+		if !s.closed.CompareAndSwap(false, true) {
+			return io.ErrClosedPipe
+		}
+	} else if s.closed.Load() {
+		return io.ErrClosedPipe
+	}
+
+	output, err := s.transform(p, final)
+	if err != nil {
+		s.closed.Store(true)
+		s.writer.CloseWithError(err)
+		return err
+	}
+	if len(output) > 0 {
+		if _, err := s.writer.Write(output); err != nil {
+			return err
+		}
+	}
+	if final {
+		return s.writer.Close()
+	}
+	return nil
+}
+
+// Read reads transformed output. It returns io.EOF once the stream has been
+// closed and all output read.
+func (s *transformStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// Write transforms p. It blocks until readers have taken all the output,
+// so writers and readers must run in different goroutines. It reports
+// len(p) on success, even though an incomplete character at the end of p
+// is held back until the next write.
+func (s *transformStream) Write(p []byte) (int, error) {
+	if err := s.write(p, false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flushes any held back input, which may be an error if it is
+// incomplete, and then signals io.EOF to readers
+func (s *transformStream) Close() error {
+	return s.write(nil, true)
+}
+
+// CloseWithError aborts the stream: held back input is dropped and readers
+// get err, or io.EOF if err is nil
+func (s *transformStream) CloseWithError(err error) error {
+	s.closed.Store(true)
+	return s.writer.CloseWithError(err)
+}
+
+// CloseRead cancels the stream from the reading side. Blocked and later
+// writes return io.ErrClosedPipe.
+func (s *transformStream) CloseRead() error {
+	return s.reader.Close()
+}
+
+// DecoderStream is a transform stream in the manner of the web's
+// TextDecoderStream: bytes written to it are decoded as an
+// IncrementalDecoder would, and the UTF-8 text is read from it. Writes
+// block until the text has been read, which applies backpressure, and
+// Close flushes the decoder. Write and Close must not be called
+// concurrently, but Read may be called from another goroutine.
+type DecoderStream struct {
+	*transformStream
+	decoder *IncrementalDecoder
+}
+
+// NewDecoderStream returns a DecoderStream that sniffs a BOM and otherwise
+// uses fallbackEncoding. errors defaults to "replace".
+func NewDecoderStream(fallbackEncoding interface{}, errors string) (*DecoderStream, error) {
+	decoder, err := NewIncrementalDecoder(fallbackEncoding, errors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecoderStream{
+		transformStream: newTransformStream(func(input []byte, final bool) ([]byte, error) {
+			decoded, err := decoder.Decode(input, final)
+			return []byte(decoded), err
+		}),
+		decoder: decoder,
+	}, nil
+}
+
+// Encoding returns the encoding being used, or nil until enough input has
+// been written to sniff the BOM
+func (s *DecoderStream) Encoding() *EncodingInfo {
+	return s.decoder.Encoding
+}
+
+// EncoderStream is a transform stream in the manner of the web's
+// TextEncoderStream, for any encoding: UTF-8 text written to it is encoded
+// as an IncrementalEncoder would, and the bytes are read from it. It blocks
+// and closes like DecoderStream.
+type EncoderStream struct {
+	*transformStream
+	encoding *EncodingInfo
+}
+
+// NewEncoderStream returns an EncoderStream for encoding. errors defaults
+// to "strict".
+func NewEncoderStream(encoding interface{}, errors string) (*EncoderStream, error) {
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := NewIncrementalEncoder(enc, errors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncoderStream{
+		transformStream: newTransformStream(func(input []byte, final bool) ([]byte, error) {
+			return encoder.Encode(string(input), final)
+		}),
+		encoding: enc,
+	}, nil
+}
+
+// WriteString is like Write but takes a string
+func (s *EncoderStream) WriteString(text string) (int, error) {
+	return s.Write([]byte(text))
+}
+
+// Encoding returns the encoding being written
+func (s *EncoderStream) Encoding() *EncodingInfo {
+	return s.encoding
+}
@@ -0,0 +1,128 @@
+package webencodings
+
+import (
+	"io"
+	"testing"
+)
+
+// readResult is what readAll read
+type readResult struct {
+	text []byte
+	err  error
+}
+
+// readAll reads s to the end in a separate goroutine, as writes to s block
+// until their output has been read
+func readAll(s io.Reader) <-chan readResult {
+	result := make(chan readResult, 1)
+	go func() {
+		text, err := io.ReadAll(s)
+		result <- readResult{text, err}
+	}()
+	return result
+}
+
+func TestDecoderStream(t *testing.T) {
+	stream, err := NewDecoderStream("utf-8", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := readAll(stream)
+
+	// The BOM and a rune split across writes
+	for _, chunk := range []string{"\xFF", "\xFEa", "\x00=\xd8", "\x00\xde"} {
+		if n, err := stream.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q): unexpected %d, %v", chunk, n, err)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := <-result
+	if output.err != nil || string(output.text) != "a😀" || stream.Encoding() != utf16LE {
+		t.Errorf("expected %q in UTF-16LE, got %q in %v (%v)", "a😀", output.text, stream.Encoding(), output.err)
+	}
+
+	if _, err := stream.Write([]byte("a")); err != io.ErrClosedPipe {
+		t.Errorf("expected io.ErrClosedPipe writing after Close, got %v", err)
+	}
+}
+
+func TestDecoderStreamErrors(t *testing.T) {
+	stream, _ := NewDecoderStream("utf-8", "strict")
+	result := readAll(stream)
+	stream.Write([]byte("a\xE2\x82"))
+	if err := stream.Close(); err != ErrInvalidUTF8 {
+		t.Errorf("expected Close to report the truncated sequence, got %v", err)
+	}
+	if output := <-result; string(output.text) != "a" || output.err != ErrInvalidUTF8 {
+		t.Errorf("expected readers to get %q and ErrInvalidUTF8, got %q and %v", "a", output.text, output.err)
+	}
+
+	if _, err := NewDecoderStream("bogus", ""); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+}
+
+func TestDecoderStreamCancel(t *testing.T) {
+	stream, _ := NewDecoderStream("utf-8", "")
+	stream.CloseRead()
+	if _, err := stream.Write([]byte("abc")); err != io.ErrClosedPipe {
+		t.Errorf("expected io.ErrClosedPipe after CloseRead, got %v", err)
+	}
+
+	stream, _ = NewDecoderStream("utf-8", "")
+	result := readAll(stream)
+	stream.Write([]byte("ab\xE2"))
+	stream.CloseWithError(io.ErrUnexpectedEOF)
+	if output := <-result; string(output.text) != "ab" || output.err != io.ErrUnexpectedEOF {
+		t.Errorf("expected readers to get %q and the abort error, got %q and %v", "ab", output.text, output.err)
+	}
+
+	// Abort from the reading side while a write blocks
+	stream, _ = NewDecoderStream("utf-8", "")
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write([]byte("abc"))
+		written <- err
+	}()
+	stream.CloseWithError(io.ErrUnexpectedEOF)
+	if err := <-written; err != io.ErrClosedPipe {
+		t.Errorf("expected the blocked write to end, got %v", err)
+	}
+	if err := stream.Close(); err != io.ErrClosedPipe {
+		t.Errorf("expected io.ErrClosedPipe closing an aborted stream, got %v", err)
+	}
+}
+
+func TestEncoderStream(t *testing.T) {
+	stream, err := NewEncoderStream("x-user-defined", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Encoding().Name != "x-user-defined" {
+		t.Errorf("unexpected encoding %v", stream.Encoding())
+	}
+	result := readAll(stream)
+
+	// "\uf7ff" split across writes
+	stream.WriteString("a\xef")
+	stream.Write([]byte("\x9f\xbf"))
+	stream.WriteString("b")
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if output := <-result; string(output.text) != "a\xffb" || output.err != nil {
+		t.Errorf("expected %q, got %q (%v)", "a\xffb", output.text, output.err)
+	}
+
+	stream, _ = NewEncoderStream("x-user-defined", "")
+	result = readAll(stream)
+	if _, err := stream.WriteString("é"); err != ErrInvalidRune {
+		t.Errorf("expected ErrInvalidRune, got %v", err)
+	}
+	if output := <-result; output.err != ErrInvalidRune {
+		t.Errorf("expected readers to get ErrInvalidRune, got %v", output.err)
+	}
+}
@@ -0,0 +1,92 @@
+package webencodings
+
+import "strings"
+
+// TextDecoderOptions are the options of NewTextDecoder
+type TextDecoderOptions struct {
+	// Fatal makes Decode fail on invalid input instead of writing U+FFFD
+	Fatal bool
+	// IgnoreBOM keeps a leading byte order mark in the output
+	IgnoreBOM bool
+}
+
+// TextDecoder mirrors the TextDecoder interface of the Encoding Standard.
+// Unlike Decode and IncrementalDecoder it never sniffs a BOM to pick the
+// encoding: only the BOM of its own encoding, if it is UTF-8 or UTF-16, is
+// stripped from the output.
+type TextDecoder struct {
+	encoding *EncodingInfo
+	options  TextDecoderOptions
+	decoder  func([]byte, bool) (string, error)
+	bomSeen  bool
+}
+
+// NewTextDecoder returns a TextDecoder for the encoding with the given
+// label. Where the web API throws a RangeError, for unknown labels and
+// those of the replacement encoding, it returns ErrUnknownEncoding. An
+// encoding that this package has no codec for, such as shift_jis, fails
+// with ErrUnsupportedEncoding.
+func NewTextDecoder(label string, options TextDecoderOptions) (*TextDecoder, error) {
+	encoding := Lookup(label)
+	if encoding == nil || encoding.Name == "replacement" {
+		return nil, ErrUnknownEncoding
+	}
+	if !hasCodec(encoding) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	return &TextDecoder{
+		encoding: encoding,
+		options:  options,
+	}, nil
+}
+
+// Encoding returns the name of the decoder's encoding
+func (d *TextDecoder) Encoding() string {
+	return d.encoding.Name
+}
+
+// Fatal reports whether the decoder fails on invalid input
+func (d *TextDecoder) Fatal() bool {
+	return d.options.Fatal
+}
+
+// IgnoreBOM reports whether the decoder keeps byte order marks
+func (d *TextDecoder) IgnoreBOM() bool {
+	return d.options.IgnoreBOM
+}
+
+// Decode decodes input. With stream set, an incomplete sequence at the end
+// of input is kept for the next call; otherwise the decoder is flushed and
+// the next call starts a new stream. In fatal mode invalid input returns an
+// error, where the web API throws a TypeError; the stream carries on after
+// the invalid input unless stream is false.
+func (d *TextDecoder) Decode(input []byte, stream bool) (string, error) {
+	if d.decoder == nil {
+		errors := "replace"
+		if d.options.Fatal {
+			errors = "strict"
+		}
+		d.decoder = newChunkDecoder(d.encoding, errors)
+		d.bomSeen = false
+	}
+
+	output, err := d.decoder(input, !stream)
+	if !stream {
+		// Start over on the next call, even after an error; a streaming
+		// error keeps the stream going, as in the web API
+		d.decoder = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !d.bomSeen && !d.options.IgnoreBOM && output != "" {
+		switch d.encoding.Name {
+		case "utf-8", "utf-16le", "utf-16be":
+			output = strings.TrimPrefix(output, "\uFEFF")
+		}
+		d.bomSeen = true
+	}
+	return output, nil
+}
@@ -0,0 +1,114 @@
+package webencodings
+
+import "testing"
+
+func TestNewTextDecoder(t *testing.T) {
+	decoder, err := NewTextDecoder(" UTF8 ", TextDecoderOptions{Fatal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoder.Encoding() != "utf-8" || !decoder.Fatal() || decoder.IgnoreBOM() {
+		t.Errorf("unexpected decoder %s, fatal %v, ignoreBOM %v", decoder.Encoding(), decoder.Fatal(), decoder.IgnoreBOM())
+	}
+
+	for _, label := range []string{"", "bogus", "replacement", "iso-2022-kr", "hz-gb-2312"} {
+		if _, err := NewTextDecoder(label, TextDecoderOptions{}); err != ErrUnknownEncoding {
+			t.Errorf("NewTextDecoder(%q): expected ErrUnknownEncoding, got %v", label, err)
+		}
+	}
+	for _, label := range []string{"shift_jis", "windows-1251", "latin1"} {
+		if _, err := NewTextDecoder(label, TextDecoderOptions{}); err != ErrUnsupportedEncoding {
+			t.Errorf("NewTextDecoder(%q): expected ErrUnsupportedEncoding, got %v", label, err)
+		}
+	}
+}
+
+func TestTextDecoderBOM(t *testing.T) {
+	tests := []struct {
+		label     string
+		input     string
+		ignoreBOM bool
+		expected  string
+	}{
+		{"utf-8", "\xEF\xBB\xBFa", false, "a"},
+		{"utf-8", "\xEF\xBB\xBFa", true, "\uFEFFa"},
+		{"utf-8", "\xEF\xBB\xBF\xEF\xBB\xBFa", false, "\uFEFFa"},
+		{"utf-8", "a\xEF\xBB\xBF", false, "a\uFEFF"},
+		{"utf-16le", "\xFF\xFEa\x00", false, "a"},
+		{"utf-16be", "\xFE\xFF\x00a", false, "a"},
+		// Only the BOM of the decoder's own encoding is recognised
+		{"utf-8", "\xFF\xFEa\x00", false, "\uFFFD\uFFFDa\x00"},
+		{"utf-16le", "\xEF\xBB\xBFa", false, "\uBBEF\u61BF"},
+		{"utf-16be", "\xFF\xFE\x00a", false, "\uFFFEa"},
+		{"x-user-defined", "\xEF\xBB\xBFa", false, "\uF7EF\uF7BB\uF7BFa"},
+	}
+
+	for _, test := range tests {
+		decoder, _ := NewTextDecoder(test.label, TextDecoderOptions{IgnoreBOM: test.ignoreBOM})
+		result, err := decoder.Decode([]byte(test.input), false)
+		if err != nil || result != test.expected {
+			t.Errorf("Decode(%q) in %s: expected %q, got %q (%v)", test.input, test.label, test.expected, result, err)
+		}
+	}
+}
+
+func TestTextDecoderStream(t *testing.T) {
+	decoder, _ := NewTextDecoder("utf-8", TextDecoderOptions{})
+
+	// The BOM and a rune split across chunks
+	var result string
+	for _, chunk := range []string{"\xEF", "\xBB\xBFa\xE2", "\x82", "\xAC"} {
+		output, err := decoder.Decode([]byte(chunk), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result += output
+	}
+	output, _ := decoder.Decode(nil, false)
+	if result+output != "a€" {
+		t.Errorf("expected %q, got %q", "a€", result+output)
+	}
+
+	// A flush ends the stream: the next BOM is stripped again, and an
+	// incomplete sequence at the flush is an error
+	if output, _ := decoder.Decode([]byte("\xEF\xBB\xBFb\xE2"), false); output != "b\uFFFD" {
+		t.Errorf("expected %q, got %q", "b\uFFFD", output)
+	}
+	if output, _ := decoder.Decode([]byte("\xEF\xBB\xBFc"), false); output != "c" {
+		t.Errorf("expected %q, got %q", "c", output)
+	}
+}
+
+func TestTextDecoderFatal(t *testing.T) {
+	decoder, _ := NewTextDecoder("utf-8", TextDecoderOptions{Fatal: true})
+	if _, err := decoder.Decode([]byte("a\xE2\x82"), true); err != nil {
+		t.Errorf("expected an incomplete sequence to be kept while streaming, got %v", err)
+	}
+	if _, err := decoder.Decode(nil, false); err != ErrInvalidUTF8 {
+		t.Errorf("expected ErrInvalidUTF8 at the flush, got %v", err)
+	}
+	if output, err := decoder.Decode([]byte("ok"), false); err != nil || output != "ok" {
+		t.Errorf("expected the decoder to start over after an error, got %q (%v)", output, err)
+	}
+
+	// A streaming error does not end the stream, so the BOM of a new stream
+	// is only stripped after a flush
+	decoder, _ = NewTextDecoder("utf-8", TextDecoderOptions{Fatal: true})
+	if output, err := decoder.Decode([]byte("\xEF\xBB\xBFa"), true); err != nil || output != "a" {
+		t.Errorf("expected the BOM to be stripped, got %q (%v)", output, err)
+	}
+	if _, err := decoder.Decode([]byte("\xFF"), true); err != ErrInvalidUTF8 {
+		t.Errorf("expected ErrInvalidUTF8 while streaming, got %v", err)
+	}
+	if output, err := decoder.Decode([]byte("\xEF\xBB\xBFb"), false); err != nil || output != "\uFEFFb" {
+		t.Errorf("expected the stream to carry on after an error, got %q (%v)", output, err)
+	}
+	if output, err := decoder.Decode([]byte("\xEF\xBB\xBFc"), false); err != nil || output != "c" {
+		t.Errorf("expected a new stream after a flush, got %q (%v)", output, err)
+	}
+
+	decoder, _ = NewTextDecoder("utf-16le", TextDecoderOptions{Fatal: true})
+	if _, err := decoder.Decode([]byte("\x00\xD8"), false); err != ErrInvalidUTF16 {
+		t.Errorf("expected ErrInvalidUTF16 for a lone surrogate, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package webencodings
+
+import "unicode/utf8"
+
+// TextEncoder mirrors the TextEncoder interface of the Encoding Standard,
+// which always encodes to UTF-8. JavaScript strings may hold lone
+// surrogates; in Go strings these appear as their three-byte WTF-8 form,
+// and TextEncoder replaces them with U+FFFD as the web API does. A
+// surrogate pair written as two such sequences is joined into one scalar
+// value, and any other invalid UTF-8 is replaced per maximal subpart.
+type TextEncoder struct{}
+
+// NewTextEncoder returns a TextEncoder
+func NewTextEncoder() *TextEncoder {
+	return &TextEncoder{}
+}
+
+// Encoding returns "utf-8", the only encoding TextEncoder supports
+func (e *TextEncoder) Encoding() string {
+	return "utf-8"
+}
+
+// Encode returns the UTF-8 encoding of input
+func (e *TextEncoder) Encode(input string) []byte {
+	if utf8.ValidString(input) {
+		return []byte(input)
+	}
+
+	output := make([]byte, 0, len(input))
+	for len(input) > 0 {
+		r, size, _ := nextScalarValue(input)
+		output = utf8.AppendRune(output, r)
+		input = input[size:]
+	}
+	return output
+}
+
+// EncodeInto writes the UTF-8 encoding of as much of src as fits into dst.
+// As in the web API, read counts the UTF-16 code units of src that were
+// consumed, two for each character outside the Basic Multilingual Plane,
+// and written counts the bytes written to dst. Characters are never split.
+func (e *TextEncoder) EncodeInto(src string, dst []byte) (read, written int) {
+	for len(src) > 0 {
+		if src[0] < utf8.RuneSelf {
+			// Copy a run of ASCII, one code unit per byte
+			n := copy(dst[written:], src[:asciiPrefixLen(src)])
+			if n == 0 {
+				break
+			}
+			written += n
+			read += n
+			src = src[n:]
+			continue
+		}
+
+		r, size, units := nextScalarValue(src)
+		n := utf8.RuneLen(r)
+		if written+n > len(dst) {
+			break
+		}
+		utf8.EncodeRune(dst[written:], r)
+		written += n
+		read += units
+		src = src[size:]
+	}
+	return read, written
+}
+
+// wtf8Surrogate returns the surrogate encoded WTF-8 style by the three
+// bytes at the start of s, and false if there is none
+func wtf8Surrogate(s string) (rune, bool) {
+	if len(s) < 3 || s[0] != 0xED || s[1] < 0xA0 || s[1] > 0xBF || s[2] < 0x80 || s[2] > 0xBF {
+		return 0, false
+	}
+	return 0xD000 | rune(s[1]&0x3F)<<6 | rune(s[2]&0x3F), true
+}
+
+// nextScalarValue decodes the scalar value at the start of s, treating it
+// as WTF-8. It returns the value, the number of bytes it took, and the
+// number of UTF-16 code units it stands for. Lone surrogates and invalid
+// bytes become U+FFFD.
+func nextScalarValue(s string) (r rune, size int, units int) {
+	if s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1, 1
+	}
+
+	r, size = utf8.DecodeRuneInString(s)
+	if r != utf8.RuneError || size > 1 {
+		if r >= 0x10000 {
+			return r, size, 2
+		}
+		return r, size, 1
+	}
+
+	if high, ok := wtf8Surrogate(s); ok {
+		if low, ok := wtf8Surrogate(s[3:]); ok && high < 0xDC00 && low >= 0xDC00 {
+			return 0x10000 + (high-0xD800)<<10 + (low - 0xDC00), 6, 2
+		}
+		return utf8.RuneError, 3, 1
+	}
+
+	end := len(s)
+	if end > utf8.UTFMax {
+		end = utf8.UTFMax
+	}
+	size, _ = utf8SubpartLen([]byte(s[:end]))
+	return utf8.RuneError, size, 1
+}
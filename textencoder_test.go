@@ -0,0 +1,69 @@
+package webencodings
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextEncoderEncode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"aé€😀", "aé€😀"},
+		{"\uFFFD", "\uFFFD"},
+		{"a\xed\xa0\x80b", "a\uFFFDb"},               // lone high surrogate
+		{"a\xed\xb0\x80b", "a\uFFFDb"},               // lone low surrogate
+		{"\xed\xa0\xbd\xed\xb8\x80", "😀"},            // surrogate pair
+		{"\xed\xb8\x80\xed\xa0\xbd", "\uFFFD\uFFFD"}, // pair in the wrong order
+		{"a\x80b", "a\uFFFDb"},
+		{"\xf0\x9f\x98", "\uFFFD"},
+		{"\xc0\xaf", "\uFFFD\uFFFD"},
+	}
+
+	encoder := NewTextEncoder()
+	if encoder.Encoding() != "utf-8" {
+		t.Errorf("unexpected encoding %s", encoder.Encoding())
+	}
+	for _, test := range tests {
+		if result := encoder.Encode(test.input); string(result) != test.expected {
+			t.Errorf("Encode(%q): expected %q, got %q", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestTextEncoderEncodeInto(t *testing.T) {
+	tests := []struct {
+		input   string
+		size    int
+		read    int
+		written int
+	}{
+		{"abc", 10, 3, 3},
+		{"abc", 2, 2, 2},
+		{"aé", 2, 1, 1},
+		{"aé", 3, 2, 3},
+		{"😀a", 3, 0, 0},
+		{"😀a", 4, 2, 4},
+		{"😀a", 5, 3, 5},
+		{"\xed\xa0\x80a", 4, 2, 4},
+		{"\xed\xa0\xbd\xed\xb8\x80", 4, 2, 4},
+		{"", 4, 0, 0},
+		{"a", 0, 0, 0},
+	}
+
+	encoder := NewTextEncoder()
+	for _, test := range tests {
+		dst := make([]byte, test.size)
+		read, written := encoder.EncodeInto(test.input, dst)
+		if read != test.read || written != test.written {
+			t.Errorf("EncodeInto(%q, %d bytes): expected %d read and %d written, got %d and %d", test.input, test.size, test.read, test.written, read, written)
+			continue
+		}
+		if expected := encoder.Encode(test.input); !bytes.HasPrefix(expected, dst[:written]) {
+			t.Errorf("EncodeInto(%q, %d bytes): wrote %q, which does not start %q", test.input, test.size, dst[:written], expected)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package webencodings
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+)
+
+// Reader decodes the text of an io.Reader to UTF-8, taking its encoding
+// from a BOM or otherwise the fallback encoding
+type Reader struct {
+	decoder *IncrementalDecoder
+	reader  *decodeReader
+}
+
+// NewReader returns a Reader for r. It fails with ErrUnsupportedEncoding if
+// fallbackEncoding has no codec in this package, as the text would
+// otherwise come out undecoded. errors defaults to "replace".
+func NewReader(r io.Reader, fallbackEncoding interface{}, errors string) (*Reader, error) {
+	fallback, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCodec(fallback) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	decoder, err := NewIncrementalDecoder(fallback, errors)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{decoder: decoder, reader: newDecodeReader(r, decoder.Decode)}, nil
+}
+
+// Read reads decoded UTF-8 text into p
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+// Encoding returns the encoding being used, or nil until enough input has
+// been read to sniff the BOM
+func (r *Reader) Encoding() *EncodingInfo {
+	return r.decoder.Encoding
+}
+
+// NewCSVReader returns a csv.Reader over the text of r decoded as by
+// NewReader. A BOM is not part of the first field.
+func NewCSVReader(r io.Reader, fallbackEncoding interface{}, errors string) (*csv.Reader, error) {
+	reader, err := NewReader(r, fallbackEncoding, errors)
+	if err != nil {
+		return nil, err
+	}
+	return csv.NewReader(reader), nil
+}
+
+// NewScanner returns a bufio.Scanner over the text of r decoded as by
+// NewReader. It splits lines by default.
+func NewScanner(r io.Reader, fallbackEncoding interface{}, errors string) (*bufio.Scanner, error) {
+	reader, err := NewReader(r, fallbackEncoding, errors)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewScanner(reader), nil
+}
+
+// byteOrderMark returns the BOM of encoding, or nil if it has none
+func byteOrderMark(encoding *EncodingInfo) []byte {
+	switch encoding.Name {
+	case "utf-8":
+		return []byte{0xEF, 0xBB, 0xBF}
+	case "utf-16le":
+		return []byte{0xFF, 0xFE}
+	case "utf-16be":
+		return []byte{0xFE, 0xFF}
+	}
+	return nil
+}
+
+// Writer encodes the UTF-8 text written to it and writes it to an
+// io.Writer. Characters split across writes are held back until they are
+// complete, so Close must be called once the text has been written; it
+// does not close the underlying writer. Wrap a Writer with csv.NewWriter
+// to write CSV files in a legacy encoding.
+type Writer struct {
+	w        io.Writer
+	encoding *EncodingInfo
+	encode   func(string, bool) ([]byte, error)
+	bom      []byte
+}
+
+// NewWriter returns a Writer that encodes to encoding. If bom is true and
+// encoding is UTF-8 or UTF-16, its BOM is written before the text. It fails
+// with ErrUnsupportedEncoding if encoding has no codec in this package.
+// errors defaults to "strict".
+func NewWriter(w io.Writer, encoding interface{}, errors string, bom bool) (*Writer, error) {
+	if errors == "" {
+		errors = "strict"
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCodec(enc) {
+		return nil, ErrUnsupportedEncoding
+	}
+
+	writer := &Writer{w: w, encoding: enc}
+	if bom {
+		writer.bom = byteOrderMark(enc)
+	}
+	if enc.Name == "utf-16le" || enc.Name == "utf-16be" {
+		writer.encode = (&utf16Encoder{bigEndian: enc.Name == "utf-16be", errors: errors}).Encode
+	} else {
+		var pending string
+		writer.encode = func(input string, final bool) ([]byte, error) {
+			input = pending + input
+			pending = ""
+			if n := incompleteSuffixLen(input); n > 0 && !final {
+				pending = input[len(input)-n:]
+				input = input[:len(input)-n]
+			}
+			return AppendEncode(nil, input, enc, errors)
+		}
+	}
+	return writer, nil
+}
+
+// Encoding returns the encoding being written
+func (w *Writer) Encoding() *EncodingInfo {
+	return w.encoding
+}
+
+// write encodes text and writes the result, after the BOM if it is still
+// to be written
+func (w *Writer) write(text string, final bool) error {
+	encoded, err := w.encode(text, final)
+	if err != nil {
+		return err
+	}
+	if w.bom != nil {
+		encoded = append(w.bom, encoded...)
+		w.bom = nil
+	}
+	if len(encoded) == 0 {
+		return nil
+	}
+	_, err = w.w.Write(encoded)
+	return err
+}
+
+// Write encodes p and writes it to the underlying writer
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.write(string(p), false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteString is like Write but takes a string
+func (w *Writer) WriteString(text string) (int, error) {
+	if err := w.write(text, false); err != nil {
+		return 0, err
+	}
+	return len(text), nil
+}
+
+// Close writes any characters held back, and the BOM if nothing has been
+// written
+func (w *Writer) Close() error {
+	return w.write("", true)
+}
@@ -0,0 +1,177 @@
+package webencodings
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		input    string
+		fallback string
+		expected string
+		encoding string
+	}{
+		{"abc", "utf-8", "abc", "utf-8"},
+		{"\xef\xbb\xbfa\xc3\xa9", "x-user-defined", "aé", "utf-8"},
+		{"\xff\xfea\x00\xe9\x00", "utf-8", "aé", "utf-16le"},
+		{"a\x80", "x-user-defined", "a\uf780", "x-user-defined"},
+		{"a\xff", "utf-8", "a\uFFFD", "utf-8"},
+		{"", "utf-8", "", "utf-8"},
+	}
+
+	for _, test := range tests {
+		reader, err := NewReader(iotest.OneByteReader(strings.NewReader(test.input)), test.fallback, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := io.ReadAll(reader)
+		if err != nil || string(result) != test.expected {
+			t.Errorf("Reader(%q, %s): expected %q, got %q (%v)", test.input, test.fallback, test.expected, result, err)
+		}
+		if reader.Encoding() == nil || reader.Encoding().Name != test.encoding {
+			t.Errorf("Reader(%q, %s): expected %s, got %v", test.input, test.fallback, test.encoding, reader.Encoding())
+		}
+	}
+
+	reader, _ := NewReader(strings.NewReader("a\xff"), "utf-8", "strict")
+	if _, err := io.ReadAll(reader); err != ErrInvalidUTF8 {
+		t.Errorf("Expected ErrInvalidUTF8 in strict mode, got %v", err)
+	}
+	if _, err := NewReader(strings.NewReader(""), "bogus", ""); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+
+	// Without a codec the text would come out as the raw legacy bytes
+	for _, encoding := range []string{"shift_jis", "windows-1251", "replacement"} {
+		if _, err := NewReader(strings.NewReader("\xcf\xf0\xe8"), encoding, "strict"); err != ErrUnsupportedEncoding {
+			t.Errorf("NewReader in %s: expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+		if _, err := NewCSVReader(strings.NewReader("\xcf\xf0\xe8"), encoding, "strict"); err != ErrUnsupportedEncoding {
+			t.Errorf("NewCSVReader in %s: expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+		if _, err := NewScanner(strings.NewReader("\x82\xa0"), encoding, ""); err != ErrUnsupportedEncoding {
+			t.Errorf("NewScanner in %s: expected ErrUnsupportedEncoding, got %v", encoding, err)
+		}
+	}
+}
+
+func TestNewCSVReader(t *testing.T) {
+	input := "\xef\xbb\xbfname,note\r\n\"Zo\xc3\xab\",\"a, b\"\r\n"
+	reader, err := NewCSVReader(strings.NewReader(input), "x-user-defined", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := reader.ReadAll()
+	expected := [][]string{{"name", "note"}, {"Zoë", "a, b"}}
+	if err != nil || !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %q, got %q (%v)", expected, records, err)
+	}
+
+	reader, _ = NewCSVReader(strings.NewReader("\x80,b\n"), "x-user-defined", "")
+	if record, err := reader.Read(); err != nil || !reflect.DeepEqual(record, []string{"\uf780", "b"}) {
+		t.Errorf("x-user-defined: got %q (%v)", record, err)
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	input := "\xfe\xff\x00a\x00\n\x00\xe9\x00\r\x00\n\x00b"
+	scanner, err := NewScanner(strings.NewReader(input), "utf-8", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	expected := []string{"a", "é", "b"}
+	if scanner.Err() != nil || !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q (%v)", expected, lines, scanner.Err())
+	}
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		input    string
+		encoding string
+		errors   string
+		bom      bool
+		expected string
+	}{
+		{"aé", "utf-8", "", false, "a\xc3\xa9"},
+		{"aé", "utf-8", "", true, "\xef\xbb\xbfa\xc3\xa9"},
+		{"aé", "utf-16le", "", true, "\xff\xfea\x00\xe9\x00"},
+		{"aé", "utf-16be", "", true, "\xfe\xff\x00a\x00\xe9"},
+		{"", "utf-16le", "", true, "\xff\xfe"},
+		{"a\uf780", "x-user-defined", "", true, "a\x80"},
+		{"é\uf780", "x-user-defined", "html", false, "&#233;\x80"},
+		{"é\uf780", "x-user-defined", "ignore", false, "\x80"},
+	}
+
+	for _, test := range tests {
+		// Write a byte at a time to split characters
+		for _, chunkSize := range []int{1, len(test.input) + 1} {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, test.encoding, test.errors, test.bom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < len(test.input); i += chunkSize {
+				if _, err := writer.Write([]byte(test.input[i:min(i+chunkSize, len(test.input))])); err != nil {
+					t.Errorf("Writer(%q, %s): unexpected error %v", test.input, test.encoding, err)
+				}
+			}
+			if err := writer.Close(); err != nil || buf.String() != test.expected {
+				t.Errorf("Writer(%q, %s, %s): expected %q, got %q (%v)", test.input, test.encoding, test.errors, test.expected, buf.String(), err)
+			}
+		}
+	}
+
+	writer, _ := NewWriter(io.Discard, "x-user-defined", "", false)
+	if _, err := writer.WriteString("é"); err == nil {
+		t.Errorf("Expected an error encoding an unencodable character in strict mode")
+	}
+	if _, err := NewWriter(io.Discard, "bogus", "", false); err != ErrUnknownEncoding {
+		t.Errorf("Expected ErrUnknownEncoding, got %v", err)
+	}
+	for _, encoding := range []string{"shift_jis", "windows-1251", "replacement"} {
+		var buf bytes.Buffer
+		if _, err := NewWriter(&buf, encoding, "strict", true); err != ErrUnsupportedEncoding || buf.Len() != 0 {
+			t.Errorf("NewWriter in %s: expected ErrUnsupportedEncoding and no output, got %v and %q", encoding, err, buf.String())
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	records := [][]string{{"id", "name"}, {"1", "café, \"bar\""}, {"2", "\U0001F600"}}
+	for _, encoding := range []string{"utf-8", "utf-16le", "utf-16be"} {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, encoding, "", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csvWriter := csv.NewWriter(writer)
+		csvWriter.WriteAll(records)
+		if err := csvWriter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if writer.Encoding().Name != encoding {
+			t.Errorf("expected %s, got %s", encoding, writer.Encoding().Name)
+		}
+
+		// The BOM overrides the fallback encoding
+		reader, _ := NewCSVReader(&buf, "x-user-defined", "strict")
+		result, err := reader.ReadAll()
+		if err != nil || !reflect.DeepEqual(result, records) {
+			t.Errorf("round trip through %s: expected %q, got %q (%v)", encoding, records, result, err)
+		}
+	}
+}
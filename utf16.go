@@ -0,0 +1,222 @@
+package webencodings
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF16 is returned when strict decoding meets an unpaired surrogate or a truncated code unit
+var ErrInvalidUTF16 = errors.New("webencodings: invalid UTF-16")
+
+// utf16Decoder implements the spec's shared UTF-16 decoder. Besides the
+// usual error modes it accepts "surrogatepass", as in Python, which writes
+// unpaired surrogates to the output in their three-byte WTF-8 form so that
+// they survive a round trip through the UTF-16 encoder.
+type utf16Decoder struct {
+	bigEndian bool
+	errors    string
+	// leadByte is the first byte of an incomplete code unit, or -1
+	leadByte int
+	// leadSurrogate is a high surrogate waiting for its pair, or 0
+	leadSurrogate rune
+}
+
+// newUTF16Decoder creates a new UTF-16 decoder
+func newUTF16Decoder(bigEndian bool, errors string) *utf16Decoder {
+	return &utf16Decoder{
+		bigEndian: bigEndian,
+		errors:    errors,
+		leadByte:  -1,
+	}
+}
+
+// appendDecode decodes input and appends the UTF-8 result to dst
+func (d *utf16Decoder) appendDecode(dst []byte, input []byte, final bool) ([]byte, error) {
+	if d.errors != "strict" && d.errors != "ignore" && d.errors != "replace" && d.errors != "surrogatepass" {
+		return dst, ErrInvalidByte
+	}
+
+	for _, b := range input {
+		if d.leadByte < 0 {
+			d.leadByte = int(b)
+			continue
+		}
+
+		var unit rune
+		if d.bigEndian {
+			unit = rune(d.leadByte)<<8 | rune(b)
+		} else {
+			unit = rune(b)<<8 | rune(d.leadByte)
+		}
+		d.leadByte = -1
+
+		if d.leadSurrogate != 0 {
+			lead := d.leadSurrogate
+			d.leadSurrogate = 0
+			if unit >= 0xDC00 && unit <= 0xDFFF {
+				dst = utf8.AppendRune(dst, 0x10000+(lead-0xD800)<<10+(unit-0xDC00))
+				continue
+			}
+			// The unpaired lead is an error; unit is then decoded afresh
+			var err error
+			if dst, err = d.unpaired(dst, lead); err != nil {
+				return dst, err
+			}
+		}
+
+		switch {
+		case unit >= 0xD800 && unit <= 0xDBFF:
+			d.leadSurrogate = unit
+		case unit >= 0xDC00 && unit <= 0xDFFF:
+			var err error
+			if dst, err = d.unpaired(dst, unit); err != nil {
+				return dst, err
+			}
+		default:
+			dst = utf8.AppendRune(dst, unit)
+		}
+	}
+
+	if final && d.leadSurrogate != 0 {
+		lead := d.leadSurrogate
+		d.leadSurrogate = 0
+		var err error
+		if dst, err = d.unpaired(dst, lead); err != nil {
+			d.leadByte = -1
+			return dst, err
+		}
+	}
+	if final && d.leadByte >= 0 {
+		d.leadByte = -1
+		return d.error(dst)
+	}
+	return dst, nil
+}
+
+// unpaired handles an unpaired surrogate according to the error mode
+func (d *utf16Decoder) unpaired(dst []byte, surrogate rune) ([]byte, error) {
+	if d.errors == "surrogatepass" {
+		return appendWTF8Surrogate(dst, surrogate), nil
+	}
+	return d.error(dst)
+}
+
+// appendWTF8Surrogate appends the WTF-8 form of a surrogate code point,
+// which is its UTF-8 form had surrogates been allowed
+func appendWTF8Surrogate(dst []byte, surrogate rune) []byte {
+	return append(dst, 0xE0|byte(surrogate>>12), 0x80|byte(surrogate>>6)&0x3F, 0x80|byte(surrogate)&0x3F)
+}
+
+// error handles one decoding error according to the error mode. A
+// truncated code unit cannot pass, so surrogatepass treats it as strict.
+func (d *utf16Decoder) error(dst []byte) ([]byte, error) {
+	switch d.errors {
+	case "strict", "surrogatepass":
+		return dst, ErrInvalidUTF16
+	case "replace":
+		return append(dst, "\uFFFD"...), nil
+	}
+	return dst, nil
+}
+
+// Decode decodes one chunk of input
+func (d *utf16Decoder) Decode(input []byte, final bool) (string, error) {
+	result, err := d.appendDecode(make([]byte, 0, len(input)*3/2), input, final)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// appendEncodeUTF16 encodes input as UTF-16 and appends the result to dst.
+// Unpaired surrogates in their WTF-8 form are encoded as they are in
+// "surrogatepass" mode and are otherwise invalid input, which is handled
+// according to errors: strict fails with ErrInvalidUTF8, replace and html
+// write U+FFFD, and ignore drops it. On error dst is returned unchanged.
+func appendEncodeUTF16(dst []byte, input string, bigEndian bool, errors string) ([]byte, error) {
+	if errors != "strict" && errors != "ignore" && errors != "replace" && errors != "html" && errors != "surrogatepass" {
+		return dst, ErrInvalidByte
+	}
+
+	result := dst
+	appendUnit := func(unit rune) {
+		if bigEndian {
+			result = append(result, byte(unit>>8), byte(unit))
+		} else {
+			result = append(result, byte(unit), byte(unit>>8))
+		}
+	}
+
+	for len(input) > 0 {
+		if input[0] < utf8.RuneSelf {
+			n := asciiPrefixLen(input)
+			for i := 0; i < n; i++ {
+				appendUnit(rune(input[i]))
+			}
+			input = input[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(input)
+		if r == utf8.RuneError && size == 1 {
+			if surrogate, ok := wtf8Surrogate(input); ok && errors == "surrogatepass" {
+				appendUnit(surrogate)
+				input = input[3:]
+				continue
+			}
+
+			end := len(input)
+			if end > utf8.UTFMax {
+				end = utf8.UTFMax
+			}
+			size, _ = utf8SubpartLen([]byte(input[:end]))
+			switch errors {
+			case "strict", "surrogatepass":
+				return dst, ErrInvalidUTF8
+			case "ignore":
+				input = input[size:]
+				continue
+			}
+		}
+		input = input[size:]
+
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			appendUnit(r1)
+			appendUnit(r2)
+		} else {
+			appendUnit(r)
+		}
+	}
+	return result, nil
+}
+
+// utf16Encoder is an incremental UTF-16 encoder that holds back a UTF-8
+// sequence split across chunks
+type utf16Encoder struct {
+	bigEndian bool
+	errors    string
+	pending   string
+}
+
+// Encode encodes one chunk of input
+func (e *utf16Encoder) Encode(input string, final bool) ([]byte, error) {
+	input = e.pending + input
+	e.pending = ""
+	if !final {
+		n := incompleteSuffixLen(input)
+		if e.errors == "surrogatepass" {
+			// utf8 sees the start of a WTF-8 surrogate as an error, not as
+			// incomplete
+			if end := len(input); end >= 1 && input[end-1] == 0xED {
+				n = 1
+			} else if end >= 2 && input[end-2] == 0xED && input[end-1] >= 0xA0 && input[end-1] <= 0xBF {
+				n = 2
+			}
+		}
+		e.pending = input[len(input)-n:]
+		input = input[:len(input)-n]
+	}
+	return appendEncodeUTF16(make([]byte, 0, len(input)*2), input, e.bigEndian, e.errors)
+}
@@ -0,0 +1,175 @@
+package webencodings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeUTF16(t *testing.T) {
+	tests := []struct {
+		input    string
+		label    string
+		expected string
+	}{
+		{"\xe9\x00", "utf-16le", "é"},
+		{"\x00\xe9", "utf-16be", "é"},
+		{"=\xd8\x00\xde", "utf-16le", "😀"},
+		{"\xd8=\xde\x00", "utf-16be", "😀"},
+		{"a\x00b", "utf-16le", "a�"},
+		{"=\xd8a\x00", "utf-16le", "�a"},
+		{"\x00\xdea\x00", "utf-16le", "�a"},
+		{"=\xd8", "utf-16le", "�"},
+		{"=\xd8=\xd8\x00\xde", "utf-16le", "�😀"},
+	}
+
+	for _, test := range tests {
+		decoded, _, err := Decode([]byte(test.input), test.label, "")
+		if err != nil {
+			t.Errorf("Decode(%q, %s) failed: %v", test.input, test.label, err)
+		}
+		if decoded != test.expected {
+			t.Errorf("Decode(%q, %s) = %q, expected %q", test.input, test.label, decoded, test.expected)
+		}
+
+		_, _, err = Decode([]byte(test.input), test.label, "strict")
+		if invalid := strings.Contains(test.expected, "�"); invalid != (err == ErrInvalidUTF16) {
+			t.Errorf("Decode(%q, %s, strict): unexpected error %v", test.input, test.label, err)
+		}
+	}
+}
+
+func TestIncrementalDecoderUTF16Splits(t *testing.T) {
+	input := "\xff\xfea\x00\xe9\x00=\xd8\x00\xde\x00\xdeb\x00"
+	expected := "aé😀�b"
+
+	for i := 0; i <= len(input); i++ {
+		for j := i; j <= len(input); j++ {
+			decoder, err := NewIncrementalDecoder("x-user-defined", "")
+			if err != nil {
+				t.Fatalf("Failed to create decoder: %v", err)
+			}
+
+			var result strings.Builder
+			for k, chunk := range []string{input[:i], input[i:j], input[j:]} {
+				decoded, err := decoder.Decode([]byte(chunk), k == 2)
+				if err != nil {
+					t.Fatalf("Decode failed for split %d/%d: %v", i, j, err)
+				}
+				result.WriteString(decoded)
+			}
+			if result.String() != expected {
+				t.Errorf("Split %d/%d: expected %q, got %q", i, j, expected, result.String())
+			}
+			if decoder.Encoding.Name != "utf-16le" {
+				t.Errorf("Split %d/%d: expected utf-16le, got %s", i, j, decoder.Encoding.Name)
+			}
+		}
+	}
+}
+
+func TestDecodeUTF16SurrogatePass(t *testing.T) {
+	tests := []struct {
+		input    string
+		label    string
+		expected string
+	}{
+		{"=\xd8\x00\xde", "utf-16le", "😀"},
+		{"=\xd8a\x00", "utf-16le", "\xed\xa0\xbda"},
+		{"\x00\xdea\x00", "utf-16le", "\xed\xb8\x80a"},
+		{"=\xd8", "utf-16le", "\xed\xa0\xbd"},
+		{"\xd8=", "utf-16be", "\xed\xa0\xbd"},
+		{"\x00\xde=\xd8", "utf-16le", "\xed\xb8\x80\xed\xa0\xbd"},
+	}
+
+	for _, test := range tests {
+		decoded, _, err := Decode([]byte(test.input), test.label, "surrogatepass")
+		if err != nil || decoded != test.expected {
+			t.Errorf("Decode(%q, %s, surrogatepass): expected %q, got %q (%v)", test.input, test.label, test.expected, decoded, err)
+		}
+	}
+
+	// A truncated code unit cannot be represented
+	if _, _, err := Decode([]byte("a\x00b"), "utf-16le", "surrogatepass"); err != ErrInvalidUTF16 {
+		t.Errorf("expected ErrInvalidUTF16 for a truncated code unit, got %v", err)
+	}
+}
+
+func TestEncodeUTF16(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   string
+		expected string
+		err      error
+	}{
+		{"aé😀", "", "a\x00\xe9\x00=\xd8\x00\xde", nil},
+		{"a\xed\xa0\xbdb", "surrogatepass", "a\x00=\xd8b\x00", nil},
+		{"a\xed\xa0\xbdb", "strict", "", ErrInvalidUTF8},
+		{"a\xed\xa0\xbdb", "replace", "a\x00\xfd\xff\xfd\xff\xfd\xffb\x00", nil},
+		{"a\x80b", "ignore", "a\x00b\x00", nil},
+		{"a\x80b", "html", "a\x00\xfd\xffb\x00", nil},
+		{"a\x80b", "surrogatepass", "", ErrInvalidUTF8},
+		{"a", "bogus", "", ErrInvalidByte},
+	}
+
+	for _, test := range tests {
+		encoded, err := Encode(test.input, "utf-16le", test.errors)
+		if err != test.err || string(encoded) != test.expected {
+			t.Errorf("Encode(%q, utf-16le, %s): expected %q and %v, got %q and %v", test.input, test.errors, test.expected, test.err, encoded, err)
+		}
+	}
+
+	if encoded, _ := Encode("é😀", "utf-16be", ""); string(encoded) != "\x00\xe9\xd8=\xde\x00" {
+		t.Errorf("Encode in utf-16be: got %q", encoded)
+	}
+	if encoded, _ := AppendEncode([]byte("x"), "é", "utf-16be", ""); string(encoded) != "x\x00\xe9" {
+		t.Errorf("AppendEncode in utf-16be: got %q", encoded)
+	}
+}
+
+func TestUTF16SurrogatePassRoundTrip(t *testing.T) {
+	// A Windows file name with an unpaired surrogate
+	input := "f\x00=\xd8.\x00\x00\xdc\x00\xdc=\xd8\x00\xde"
+	for _, label := range []string{"utf-16le", "utf-16be"} {
+		data := []byte(input)
+		if label == "utf-16be" {
+			for i := 0; i < len(data); i += 2 {
+				data[i], data[i+1] = data[i+1], data[i]
+			}
+		}
+
+		decoded, _, err := Decode(data, label, "surrogatepass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := Encode(decoded, label, "surrogatepass")
+		if err != nil || string(encoded) != string(data) {
+			t.Errorf("round trip through %s: expected %q, got %q (%v)", label, data, encoded, err)
+		}
+	}
+}
+
+func TestIncrementalEncoderUTF16Splits(t *testing.T) {
+	input := "aé\xed\xa0\xbd😀b"
+	expected := "a\x00\xe9\x00=\xd8=\xd8\x00\xdeb\x00"
+
+	for i := 0; i <= len(input); i++ {
+		for j := i; j <= len(input); j++ {
+			encoder, err := NewIncrementalEncoder("utf-16le", "surrogatepass")
+			if err != nil {
+				t.Fatalf("Failed to create encoder: %v", err)
+			}
+
+			var result []byte
+			for k, chunk := range []string{input[:i], input[i:j], input[j:]} {
+				encoded, err := encoder.Encode(chunk, k == 2)
+				if err != nil {
+					t.Fatalf("Encode failed for split %d/%d: %v", i, j, err)
+				}
+				result = append(result, encoded...)
+			}
+			if string(result) != expected {
+				t.Errorf("Split %d/%d: expected %q, got %q", i, j, expected, result)
+			}
+		}
+	}
+}
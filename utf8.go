@@ -0,0 +1,111 @@
+package webencodings
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is returned when strict decoding meets bytes that are not valid UTF-8
+var ErrInvalidUTF8 = errors.New("webencodings: invalid UTF-8")
+
+// utf8SubpartLen returns the length of the maximal subpart at the start of
+// s, the longest prefix that could begin a valid UTF-8 sequence, which the
+// spec's UTF-8 decoder replaces with a single U+FFFD. truncated reports that
+// the subpart runs to the end of s and could still be completed.
+func utf8SubpartLen(s []byte) (n int, truncated bool) {
+	need := 0
+	lower, upper := byte(0x80), byte(0xBF)
+	switch b := s[0]; {
+	case b >= 0xC2 && b <= 0xDF:
+		need = 1
+	case b >= 0xE0 && b <= 0xEF:
+		need = 2
+		if b == 0xE0 {
+			lower = 0xA0
+		} else if b == 0xED {
+			upper = 0x9F
+		}
+	case b >= 0xF0 && b <= 0xF4:
+		need = 3
+		if b == 0xF0 {
+			lower = 0x90
+		} else if b == 0xF4 {
+			upper = 0x8F
+		}
+	default:
+		return 1, false
+	}
+
+	for n = 1; n <= need; n++ {
+		if n == len(s) {
+			return n, true
+		}
+		if s[n] < lower || s[n] > upper {
+			return n, false
+		}
+		lower, upper = 0x80, 0xBF
+	}
+	return n, false
+}
+
+// appendDecodeUTF8 implements the spec's UTF-8 decoder: it appends the
+// valid UTF-8 in input to dst and handles each maximal subpart of invalid
+// input according to errors. Unless final is set, an incomplete sequence at
+// the end of input is left undecoded and its length returned as pending.
+func appendDecodeUTF8(dst []byte, input []byte, errors string, final bool) (result []byte, pending int, err error) {
+	if errors != "strict" && errors != "ignore" && errors != "replace" {
+		return dst, 0, ErrInvalidByte
+	}
+
+	for len(input) > 0 {
+		if input[0] < utf8.RuneSelf {
+			n := asciiPrefixLen(input)
+			dst = append(dst, input[:n]...)
+			input = input[n:]
+			continue
+		}
+
+		if r, size := utf8.DecodeRune(input); r != utf8.RuneError || size > 1 {
+			dst = append(dst, input[:size]...)
+			input = input[size:]
+			continue
+		}
+
+		n, truncated := utf8SubpartLen(input)
+		if truncated && !final {
+			return dst, n, nil
+		}
+		switch errors {
+		case "strict":
+			return dst, 0, ErrInvalidUTF8
+		case "replace":
+			dst = append(dst, "\uFFFD"...)
+		}
+		input = input[n:]
+	}
+
+	return dst, 0, nil
+}
+
+// utf8Decoder is an incremental UTF-8 decoder
+type utf8Decoder struct {
+	errors  string
+	pending []byte
+}
+
+// Decode decodes one chunk of input
+func (d *utf8Decoder) Decode(input []byte, final bool) (string, error) {
+	if len(d.pending) > 0 {
+		input = append(d.pending, input...)
+		d.pending = nil
+	}
+
+	result, pending, err := appendDecodeUTF8(make([]byte, 0, len(input)), input, d.errors, final)
+	if err != nil {
+		return "", err
+	}
+	if pending > 0 {
+		d.pending = append([]byte(nil), input[len(input)-pending:]...)
+	}
+	return string(result), nil
+}
@@ -0,0 +1,96 @@
+package webencodings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeUTF8(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"é€😀", "é€😀"},
+		{"�", "�"},
+		{"a\x80b", "a�b"},
+		{"a\xffb", "a�b"},
+		// Maximal subparts are replaced with a single U+FFFD
+		{"\xe2\x82", "�"},
+		{"\xe2\x82a", "�a"},
+		{"\xf0\x9f\x98", "�"},
+		{"\xf0\x9f\x98\xe2\x82\xac", "�€"},
+		// Overlong forms, surrogates and values above U+10FFFF
+		{"\xc0\xaf", "��"},
+		{"\xe0\x80\xaf", "���"},
+		{"\xed\xa0\x80", "���"},
+		{"\xf4\x90\x80\x80", "����"},
+		{"\xf5\x80", "��"},
+	}
+
+	for _, test := range tests {
+		decoded, _, err := Decode([]byte(test.input), "utf-8", "replace")
+		if err != nil {
+			t.Errorf("Decode(%q) failed: %v", test.input, err)
+		}
+		if decoded != test.expected {
+			t.Errorf("Decode(%q) = %q, expected %q", test.input, decoded, test.expected)
+		}
+
+		decoded, _, err = Decode([]byte(test.input), "utf-8", "ignore")
+		if err != nil {
+			t.Errorf("Decode(%q) failed: %v", test.input, err)
+		}
+		expected := test.input
+		if test.input != test.expected {
+			expected = strings.ReplaceAll(test.expected, "�", "")
+		}
+		if decoded != expected {
+			t.Errorf("Decode(%q, ignore) = %q, expected %q", test.input, decoded, expected)
+		}
+
+		_, _, err = Decode([]byte(test.input), "utf-8", "strict")
+		if valid := test.input == test.expected; valid != (err == nil) {
+			t.Errorf("Decode(%q, strict): unexpected error %v", test.input, err)
+		}
+		if err != nil && err != ErrInvalidUTF8 {
+			t.Errorf("Decode(%q, strict): expected %v, got %v", test.input, ErrInvalidUTF8, err)
+		}
+	}
+}
+
+func TestIncrementalDecoderUTF8Splits(t *testing.T) {
+	input := "aé€😀\xe2\x82b"
+	expected := "aé€😀�b"
+
+	for i := 0; i <= len(input); i++ {
+		for j := i; j <= len(input); j++ {
+			decoder, err := NewIncrementalDecoder("utf-8", "")
+			if err != nil {
+				t.Fatalf("Failed to create decoder: %v", err)
+			}
+
+			var result strings.Builder
+			for k, chunk := range []string{input[:i], input[i:j], input[j:]} {
+				decoded, err := decoder.Decode([]byte(chunk), k == 2)
+				if err != nil {
+					t.Fatalf("Decode failed for split %d/%d: %v", i, j, err)
+				}
+				result.WriteString(decoded)
+			}
+			if result.String() != expected {
+				t.Errorf("Split %d/%d: expected %q, got %q", i, j, expected, result.String())
+			}
+		}
+	}
+
+	// A truncated sequence is an error only at the end of the input
+	decoder, _ := NewIncrementalDecoder("utf-8", "strict")
+	if _, err := decoder.Decode([]byte("a\xe2\x82"), false); err != nil {
+		t.Errorf("Decode failed: %v", err)
+	}
+	if _, err := decoder.Decode(nil, true); err != ErrInvalidUTF8 {
+		t.Errorf("Expected %v, got %v", ErrInvalidUTF8, err)
+	}
+}
@@ -13,6 +13,9 @@ var (
 	ErrUnknownEncoding = errors.New("webencodings: unknown encoding label")
 	// ErrShortWrite is returned when not all data could be written
 	ErrShortWrite = errors.New("webencodings: short write")
+	// ErrUnsupportedEncoding is returned where an encoding has to be
+	// converted but this package has no codec for it
+	ErrUnsupportedEncoding = errors.New("webencodings: no codec for encoding")
 )
 
 // PythonNames maps some encoding names that are not valid Python aliases
@@ -36,6 +39,25 @@ func (e *EncodingInfo) String() string {
 	return "<Encoding " + e.Name + ">"
 }
 
+// Confidence is how sure a sniffing step is of the encoding it found, as in
+// the HTML standard's "confidence" of a character encoding
+type Confidence int
+
+const (
+	// Tentative encodings may still be changed, e.g. by a later <meta> tag
+	Tentative Confidence = iota
+	// Certain encodings come from a BOM or the transport layer and are final
+	Certain
+)
+
+// String returns the spec's name for the confidence
+func (c Confidence) String() string {
+	if c == Certain {
+		return "certain"
+	}
+	return "tentative"
+}
+
 // Cache stores encoding objects to avoid repeated lookups
 var Cache = make(map[string]*EncodingInfo)
 
@@ -134,6 +156,16 @@ func DetectBOM(input []byte) (*EncodingInfo, []byte) {
 
 // Decode decodes a single byte string
 func Decode(input []byte, fallbackEncoding interface{}, errors string) (string, *EncodingInfo, error) {
+	decoded, result, err := DecodeWithResult(input, fallbackEncoding, errors)
+	if result == nil {
+		return decoded, nil, err
+	}
+	return decoded, result.Encoding, err
+}
+
+// DecodeWithResult is like Decode but reports whether the encoding came
+// from a BOM or the fallback
+func DecodeWithResult(input []byte, fallbackEncoding interface{}, errors string) (string, *DecodeResult, error) {
 	if errors == "" {
 		errors = "replace"
 	}
@@ -144,22 +176,70 @@ func Decode(input []byte, fallbackEncoding interface{}, errors string) (string,
 		return "", nil, err
 	}
 
-	bomEncoding, remaining := DetectBOM(input)
-	encoding := bomEncoding
-	if encoding == nil {
-		encoding = fallbackEnc
+	result, remaining := sniffBOM(input)
+	if result == nil {
+		result = newDecodeResult(fallbackEnc, SourceFallback)
 	}
 
+	decoded, err := decodeWith(remaining, result.Encoding, errors)
+	return decoded, result, err
+}
+
+// decodeWith decodes input with encoding, without sniffing a BOM
+func decodeWith(input []byte, encoding *EncodingInfo, errors string) (string, error) {
 	// For x-user-defined encoding
 	if encoding.Name == "x-user-defined" {
 		if codecInfo, ok := encoding.CodecInfo.(*CodecInfo); ok {
-			decoded, err := codecInfo.Decode(remaining, errors)
-			return decoded, encoding, err
+			return codecInfo.Decode(input, errors)
+		}
+	}
+
+	decoded, err := appendDecodeWith(make([]byte, 0, len(input)), input, encoding, errors)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// appendDecodeWith decodes input with encoding, without sniffing a BOM, and
+// appends the result to dst
+func appendDecodeWith(dst []byte, input []byte, encoding *EncodingInfo, errors string) ([]byte, error) {
+	switch encoding.Name {
+	case "x-user-defined":
+		if codecInfo, ok := encoding.CodecInfo.(*CodecInfo); ok {
+			return codecInfo.AppendDecode(dst, input, errors)
 		}
+	case "utf-8":
+		dst, _, err := appendDecodeUTF8(dst, input, errors, true)
+		return dst, err
+	case "utf-16le", "utf-16be":
+		return newUTF16Decoder(encoding.Name == "utf-16be", errors).appendDecode(dst, input, true)
 	}
 
 	// For other encodings, we'd need to implement Go's encoding support
-	return string(remaining), encoding, nil
+	return append(dst, input...), nil
+}
+
+// AppendDecode is like Decode but appends the decoded text to dst and
+// returns the extended buffer, so that callers can reuse their buffers
+func AppendDecode(dst []byte, input []byte, fallbackEncoding interface{}, errors string) ([]byte, *EncodingInfo, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	fallbackEnc, err := getEncoding(fallbackEncoding)
+	if err != nil {
+		return dst, nil, err
+	}
+
+	bomEncoding, remaining := DetectBOM(input)
+	encoding := bomEncoding
+	if encoding == nil {
+		encoding = fallbackEnc
+	}
+
+	dst, err = appendDecodeWith(dst, remaining, encoding, errors)
+	return dst, encoding, err
 }
 
 // Encode encodes a single string
@@ -180,18 +260,88 @@ func Encode(input string, encoding interface{}, errors string) ([]byte, error) {
 		}
 	}
 
+	switch enc.Name {
+	case "utf-16le", "utf-16be":
+		return appendEncodeUTF16(make([]byte, 0, len(input)*2), input, enc.Name == "utf-16be", errors)
+	}
+
 	// For other encodings, we'd need to implement Go's encoding support
 	return []byte(input), nil
 }
 
+// AppendEncode is like Encode but appends the encoded bytes to dst and
+// returns the extended buffer, so that callers can reuse their buffers
+func AppendEncode(dst []byte, input string, encoding interface{}, errors string) ([]byte, error) {
+	if errors == "" {
+		errors = "strict"
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		return dst, err
+	}
+
+	if enc.Name == "x-user-defined" {
+		if codecInfo, ok := enc.CodecInfo.(*CodecInfo); ok {
+			return codecInfo.AppendEncode(dst, input, errors)
+		}
+	}
+
+	switch enc.Name {
+	case "utf-16le", "utf-16be":
+		return appendEncodeUTF16(dst, input, enc.Name == "utf-16be", errors)
+	}
+
+	return append(dst, input...), nil
+}
+
+// encodeOrFail appends the encoding of input to dst up to the first rune
+// that encoding cannot encode, and returns that rune and the input after
+// it. The rune is -1 once all of input has been encoded. Encodings without
+// a codec are copied as UTF-8, so callers check hasCodec first.
+func encodeOrFail(dst []byte, input string, encoding *EncodingInfo) ([]byte, rune, string) {
+	if encoding.Name == "x-user-defined" {
+		if _, ok := encoding.CodecInfo.(*CodecInfo); ok {
+			return NewCodec().appendEncodeOrFail(dst, input)
+		}
+	}
+
+	return append(dst, input...), -1, ""
+}
+
+// hasCodec reports whether encoding has a codec in this package. Decode and
+// Encode pass the bytes of other encodings through unchanged.
+func hasCodec(encoding *EncodingInfo) bool {
+	switch encoding.Name {
+	case "utf-8", "utf-16le", "utf-16be", "x-user-defined":
+		return true
+	}
+	return false
+}
+
+// OutputEncoding implements the Encoding Standard's "get an output
+// encoding": UTF-16 and the replacement encoding, which cannot be used to
+// encode, are replaced by UTF-8
+func OutputEncoding(encoding *EncodingInfo) *EncodingInfo {
+	switch encoding.Name {
+	case "replacement", "utf-16be", "utf-16le":
+		return UTF8
+	}
+	return encoding
+}
+
 // IncrementalDecoder provides "push"-based decoding
 type IncrementalDecoder struct {
 	fallbackEncoding *EncodingInfo
 	errors           string
 	buffer           []byte
 	decoder          func([]byte, bool) (string, error)
+	// nonASCII is set once a non-ASCII byte has been passed to decoder
+	nonASCII bool
 	// Encoding is the actual encoding being used, or nil if not determined yet
 	Encoding *EncodingInfo
+	// Confidence is Certain once Encoding comes from a BOM or ChangeEncoding
+	Confidence Confidence
 }
 
 // NewIncrementalDecoder creates a new incremental decoder
@@ -217,6 +367,7 @@ func NewIncrementalDecoder(fallbackEncoding interface{}, errors string) (*Increm
 // Decode decodes one chunk of input
 func (d *IncrementalDecoder) Decode(input []byte, final bool) (string, error) {
 	if d.decoder != nil {
+		d.nonASCII = d.nonASCII || asciiPrefixLen(input) < len(input)
 		return d.decoder(input, final)
 	}
 
@@ -232,25 +383,41 @@ func (d *IncrementalDecoder) Decode(input []byte, final bool) (string, error) {
 			// No BOM
 			encoding = d.fallbackEncoding
 		}
+	} else {
+		d.Confidence = Certain
 	}
 
+	d.decoder = newChunkDecoder(encoding, d.errors)
+	d.Encoding = encoding
+	d.buffer = nil
+	d.nonASCII = asciiPrefixLen(remaining) < len(remaining)
+	return d.decoder(remaining, final)
+}
+
+// newChunkDecoder returns an incremental decode function for encoding that
+// does not sniff a BOM
+func newChunkDecoder(encoding *EncodingInfo, errors string) func([]byte, bool) (string, error) {
 	// Set up decoder based on encoding
 	if encoding.Name == "x-user-defined" {
 		if _, ok := encoding.CodecInfo.(*CodecInfo); ok {
 			decoder := NewXUserDefinedDecoder()
-			d.decoder = func(data []byte, final bool) (string, error) {
+			return func(data []byte, final bool) (string, error) {
 				return decoder.Decode(data, final)
 			}
 		}
 	}
 
-	d.Encoding = encoding
-	if d.decoder != nil {
-		return d.decoder(remaining, final)
+	switch encoding.Name {
+	case "utf-8":
+		return (&utf8Decoder{errors: errors}).Decode
+	case "utf-16le", "utf-16be":
+		return newUTF16Decoder(encoding.Name == "utf-16be", errors).Decode
 	}
 
 	// Fallback for unsupported encodings
-	return string(remaining), nil
+	return func(data []byte, final bool) (string, error) {
+		return string(data), nil
+	}
 }
 
 // IncrementalEncoder provides "push"-based encoding
@@ -276,9 +443,11 @@ func NewIncrementalEncoder(encoding interface{}, errors string) (*IncrementalEnc
 		if _, ok := enc.CodecInfo.(*CodecInfo); ok {
 			xuEncoder := NewXUserDefinedEncoder()
 			encoder.encode = func(input string, final bool) ([]byte, error) {
-				return xuEncoder.Encode([]byte(input), final)
+				return xuEncoder.EncodeString(input, final)
 			}
 		}
+	} else if enc.Name == "utf-16le" || enc.Name == "utf-16be" {
+		encoder.encode = (&utf16Encoder{bigEndian: enc.Name == "utf-16be", errors: errors}).Encode
 	} else {
 		// Fallback for unsupported encodings
 		encoder.encode = func(input string, final bool) ([]byte, error) {
@@ -3,6 +3,7 @@ package webencodings
 import (
 	"errors"
 	"io"
+	"strconv"
 	"unicode/utf8"
 )
 
@@ -11,19 +12,13 @@ var (
 	ErrInvalidByte = errors.New("webencodings: invalid byte in x-user-defined encoding")
 	// ErrInvalidRune is returned when an invalid rune is encountered during decoding
 	ErrInvalidRune = errors.New("webencodings: invalid rune in x-user-defined encoding")
+	// ErrTruncatedInput is returned when the final chunk of input ends in the middle of a UTF-8 sequence
+	ErrTruncatedInput = errors.New("webencodings: truncated UTF-8 sequence at end of input")
 
-	// EncodingTable provides reverse lookup from rune to byte for efficient encoding
-	EncodingTable map[rune]byte
+	// xUserDefinedIndex provides reverse lookup from rune to byte for efficient encoding
+	xUserDefinedIndex = newSingleByteIndex(&DecodingTable)
 )
 
-// init initializes the encoding table for efficient lookups
-func init() {
-	EncodingTable = make(map[rune]byte, 256)
-	for i, r := range DecodingTable {
-		EncodingTable[r] = byte(i)
-	}
-}
-
 // XUserDefinedEncoder provides incremental encoding functionality
 type XUserDefinedEncoder struct {
 	pending []byte
@@ -37,30 +32,51 @@ func NewXUserDefinedEncoder() *XUserDefinedEncoder {
 	}
 }
 
-// Encode incrementally encodes input and returns the encoded bytes
+// Encode incrementally encodes UTF-8 input and returns the encoded bytes.
+// A rune split across calls is held back until its remaining bytes arrive.
 func (e *XUserDefinedEncoder) Encode(input []byte, final bool) ([]byte, error) {
-	// Combine pending bytes with new input
-	data := append(e.pending, input...)
-	e.pending = nil
+	return encodeXUserDefinedChunk(e, input, final)
+}
 
-	if !final && len(data) > 0 {
-		// Check if the last bytes form an incomplete UTF-8 sequence
-		for i := len(data) - 1; i >= 0 && i >= len(data)-4; i-- {
-			if utf8.RuneStart(data[i]) {
-				if r, size := utf8.DecodeRune(data[i:]); r == utf8.RuneError && size == 1 {
-					// Incomplete sequence, save for next call
-					e.pending = make([]byte, len(data)-i)
-					copy(e.pending, data[i:])
-					data = data[:i]
-				}
-				break
-			}
+// EncodeString is like Encode but takes a string, which may equally end in
+// the middle of a rune
+func (e *XUserDefinedEncoder) EncodeString(input string, final bool) ([]byte, error) {
+	return encodeXUserDefinedChunk(e, input, final)
+}
+
+// encodeXUserDefinedChunk implements Encode and EncodeString
+func encodeXUserDefinedChunk[T string | []byte](e *XUserDefinedEncoder, input T, final bool) ([]byte, error) {
+	data := string(input)
+	if len(e.pending) > 0 {
+		// Combine pending bytes with new input
+		data = string(e.pending) + data
+		e.pending = e.pending[:0]
+	}
+
+	if n := incompleteSuffixLen(data); n > 0 {
+		if final {
+			return nil, ErrTruncatedInput
 		}
+		// Incomplete sequence, save for next call
+		e.pending = append(e.pending, data[len(data)-n:]...)
+		data = data[:len(data)-n]
 	}
 
-	// Convert to string and encode
-	s := string(data)
-	return e.codec.Encode(s, "strict")
+	return e.codec.Encode(data, "strict")
+}
+
+// incompleteSuffixLen returns the length of the UTF-8 sequence at the end of
+// s that has a valid start byte but is still missing continuation bytes
+func incompleteSuffixLen(s string) int {
+	for i := len(s) - 1; i >= 0 && i > len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if utf8.FullRuneInString(s[i:]) {
+				return 0
+			}
+			return len(s) - i
+		}
+	}
+	return 0
 }
 
 // Reset resets the encoder state
@@ -100,46 +116,113 @@ func NewCodec() *Codec {
 
 // Encode encodes a string using the x-user-defined encoding
 func (c *Codec) Encode(input string, errors string) ([]byte, error) {
-	if errors != "strict" && errors != "ignore" && errors != "replace" {
-		return nil, ErrInvalidByte
+	result, err := c.AppendEncode(make([]byte, 0, len(input)), input, errors)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AppendEncode encodes a string using the x-user-defined encoding and
+// appends the result to dst. On error dst is returned unchanged.
+// Besides "strict", "replace" and "ignore", errors may be "html", which
+// writes runes that cannot be encoded as decimal character references.
+func (c *Codec) AppendEncode(dst []byte, input string, errors string) ([]byte, error) {
+	if errors != "strict" && errors != "ignore" && errors != "replace" && errors != "html" {
+		return dst, ErrInvalidByte
 	}
 
-	result := make([]byte, 0, len(input))
+	result := dst
+	for {
+		var r rune
+		result, r, input = c.appendEncodeOrFail(result, input)
+		if r < 0 {
+			return result, nil
+		}
 
-	for _, r := range input {
-		if b, found := EncodingTable[r]; found {
-			result = append(result, b)
-		} else {
-			if errors == "strict" {
-				return nil, ErrInvalidRune
-			} else if errors == "ignore" {
-				continue
-			} else if errors == "replace" {
-				result = append(result, '?')
-			}
+		switch errors {
+		case "strict":
+			return dst, ErrInvalidRune
+		case "replace":
+			result = append(result, '?')
+		case "html":
+			result = appendNCR(result, r)
 		}
 	}
+}
 
-	return result, nil
+// appendEncodeOrFail implements the Encoding Standard's "encode or fail":
+// it appends the encoding of input to dst up to the first rune that cannot
+// be encoded, and returns that rune and the input after it. The rune is -1
+// once all of input has been encoded.
+func (c *Codec) appendEncodeOrFail(dst []byte, input string) ([]byte, rune, string) {
+	for len(input) > 0 {
+		if input[0] < utf8.RuneSelf {
+			n := asciiPrefixLen(input)
+			dst = append(dst, input[:n]...)
+			input = input[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(input)
+		input = input[size:]
+		b, found := xUserDefinedIndex.lookup(r)
+		if !found {
+			return dst, r, input
+		}
+		dst = append(dst, b)
+	}
+	return dst, -1, ""
+}
+
+// appendNCR appends the decimal character reference for r, such as "&#233;"
+func appendNCR(dst []byte, r rune) []byte {
+	dst = append(dst, "&#"...)
+	dst = strconv.AppendInt(dst, int64(r), 10)
+	return append(dst, ';')
+}
+
+// EncodeRune returns the x-user-defined byte for r, and false if r cannot be encoded
+func (c *Codec) EncodeRune(r rune) (byte, bool) {
+	return xUserDefinedIndex.lookup(r)
 }
 
 // Decode decodes bytes using the x-user-defined encoding
 func (c *Codec) Decode(input []byte, errors string) (string, error) {
-	if errors != "strict" && errors != "ignore" && errors != "replace" {
-		return "", ErrInvalidByte
-	}
-
 	if len(input) == 0 {
+		if errors != "strict" && errors != "ignore" && errors != "replace" {
+			return "", ErrInvalidByte
+		}
 		return "", nil
 	}
 
-	result := make([]rune, 0, len(input))
+	result, err := c.AppendDecode(make([]byte, 0, len(input)), input, errors)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
 
-	for _, b := range input {
-		result = append(result, DecodingTable[b])
+// AppendDecode decodes bytes using the x-user-defined encoding and appends
+// the UTF-8 result to dst
+func (c *Codec) AppendDecode(dst []byte, input []byte, errors string) ([]byte, error) {
+	if errors != "strict" && errors != "ignore" && errors != "replace" {
+		return dst, ErrInvalidByte
 	}
 
-	return string(result), nil
+	for len(input) > 0 {
+		if input[0] < utf8.RuneSelf {
+			n := asciiPrefixLen(input)
+			dst = append(dst, input[:n]...)
+			input = input[n:]
+			continue
+		}
+
+		dst = utf8.AppendRune(dst, DecodingTable[input[0]])
+		input = input[1:]
+	}
+
+	return dst, nil
 }
 
 // StreamWriter provides streaming write functionality
@@ -224,6 +307,8 @@ type CodecInfo struct {
 	Name               string
 	Encode             func(string, string) ([]byte, error)
 	Decode             func([]byte, string) (string, error)
+	AppendEncode       func([]byte, string, string) ([]byte, error)
+	AppendDecode       func([]byte, []byte, string) ([]byte, error)
 	IncrementalEncoder func() *XUserDefinedEncoder
 	IncrementalDecoder func() *XUserDefinedDecoder
 	StreamReader       func(io.Reader) *StreamReader
@@ -234,9 +319,11 @@ type CodecInfo struct {
 func GetCodecInfo() *CodecInfo {
 	codec := NewCodec()
 	return &CodecInfo{
-		Name:   "x-user-defined",
-		Encode: codec.Encode,
-		Decode: codec.Decode,
+		Name:         "x-user-defined",
+		Encode:       codec.Encode,
+		Decode:       codec.Decode,
+		AppendEncode: codec.AppendEncode,
+		AppendDecode: codec.AppendDecode,
 		IncrementalEncoder: func() *XUserDefinedEncoder {
 			return NewXUserDefinedEncoder()
 		},
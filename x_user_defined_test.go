@@ -0,0 +1,137 @@
+package webencodings
+
+import (
+	"bytes"
+	"testing"
+)
+
+// xUserDefinedSplitInput mixes ASCII with the three-byte UTF-8 sequences
+// that x-user-defined maps onto its high bytes
+const xUserDefinedSplitInput = "a\uf780b\uf7ff\uf7c9cd"
+
+var xUserDefinedSplitExpected = []byte{'a', 0x80, 'b', 0xff, 0xc9, 'c', 'd'}
+
+func TestXUserDefinedEncoderSplits(t *testing.T) {
+	input := xUserDefinedSplitInput
+	for i := 0; i <= len(input); i++ {
+		for j := i; j <= len(input); j++ {
+			chunks := []string{input[:i], input[i:j], input[j:]}
+
+			byteEncoder := NewXUserDefinedEncoder()
+			stringEncoder := NewXUserDefinedEncoder()
+			incremental, err := NewIncrementalEncoder("x-user-defined", "")
+			if err != nil {
+				t.Fatalf("Failed to create encoder: %v", err)
+			}
+
+			var fromBytes, fromString, fromIncremental []byte
+			for k, chunk := range chunks {
+				final := k == len(chunks)-1
+
+				encoded, err := byteEncoder.Encode([]byte(chunk), final)
+				if err != nil {
+					t.Fatalf("Encode failed for split %d/%d: %v", i, j, err)
+				}
+				fromBytes = append(fromBytes, encoded...)
+
+				encoded, err = stringEncoder.EncodeString(chunk, final)
+				if err != nil {
+					t.Fatalf("EncodeString failed for split %d/%d: %v", i, j, err)
+				}
+				fromString = append(fromString, encoded...)
+
+				encoded, err = incremental.Encode(chunk, final)
+				if err != nil {
+					t.Fatalf("IncrementalEncoder failed for split %d/%d: %v", i, j, err)
+				}
+				fromIncremental = append(fromIncremental, encoded...)
+			}
+
+			for _, result := range [][]byte{fromBytes, fromString, fromIncremental} {
+				if !bytes.Equal(result, xUserDefinedSplitExpected) {
+					t.Errorf("Split %d/%d: expected %v, got %v", i, j, xUserDefinedSplitExpected, result)
+				}
+			}
+		}
+	}
+}
+
+func TestXUserDefinedEncoderTruncated(t *testing.T) {
+	for _, r := range []string{"\uf780", "é", "😀"} {
+		for n := 1; n < len(r); n++ {
+			encoder := NewXUserDefinedEncoder()
+			encoded, err := encoder.EncodeString("a"+r[:n], false)
+			if err != nil {
+				t.Errorf("Encode of %q failed: %v", r[:n], err)
+			}
+			if !bytes.Equal(encoded, []byte("a")) {
+				t.Errorf("Expected %v before truncated %q, got %v", []byte("a"), r[:n], encoded)
+			}
+
+			if _, err := encoder.EncodeString("", true); err != ErrTruncatedInput {
+				t.Errorf("Expected %v for truncated %q, got %v", ErrTruncatedInput, r[:n], err)
+			}
+
+			encoder = NewXUserDefinedEncoder()
+			if _, err := encoder.Encode([]byte(r[:n]), true); err != ErrTruncatedInput {
+				t.Errorf("Expected %v for final truncated %q, got %v", ErrTruncatedInput, r[:n], err)
+			}
+		}
+	}
+
+	// A held back sequence that is never completed is an invalid rune
+	encoder := NewXUserDefinedEncoder()
+	if _, err := encoder.EncodeString("\xef\x9e", false); err != nil {
+		t.Errorf("Encode failed: %v", err)
+	}
+	if _, err := encoder.EncodeString("a", true); err != ErrInvalidRune {
+		t.Errorf("Expected %v, got %v", ErrInvalidRune, err)
+	}
+}
+
+func TestIncompleteSuffixLen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"a\xc3", 1},
+		{"a\xc3\xa9", 0},
+		{"a\xef\x9e", 2},
+		{"a\xef\x9e\x80", 0},
+		{"\xf0\x9f\x98", 3},
+		{"\xf0\x9f\x98\x80", 0},
+		{"a\x80", 0},        // stray continuation byte
+		{"\x9f\x98\x80", 0}, // no start byte in range
+		{"a\xff", 0},        // invalid start byte
+	}
+
+	for _, test := range tests {
+		if n := incompleteSuffixLen(test.input); n != test.expected {
+			t.Errorf("incompleteSuffixLen(%q) = %d, expected %d", test.input, n, test.expected)
+		}
+	}
+}
+
+func TestCodecEncodeErrorModes(t *testing.T) {
+	codec := NewCodec()
+	tests := []struct {
+		errors   string
+		expected string
+		err      error
+	}{
+		{"strict", "", ErrInvalidRune},
+		{"replace", "a?\x80?b", nil},
+		{"ignore", "a\x80b", nil},
+		{"html", "a&#233;\x80&#128512;b", nil},
+		{"bogus", "", ErrInvalidByte},
+	}
+
+	for _, test := range tests {
+		encoded, err := codec.Encode("aé😀b", test.errors)
+		if err != test.err || string(encoded) != test.expected {
+			t.Errorf("Encode in %s mode: expected %q and %v, got %q and %v", test.errors, test.expected, test.err, encoded, err)
+		}
+	}
+}
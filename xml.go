@@ -0,0 +1,144 @@
+package webencodings
+
+import (
+	"bytes"
+	"io"
+)
+
+// xmlDeclarationLimit bounds how far SniffXMLEncoding looks for the end of
+// the XML declaration
+const xmlDeclarationLimit = 1024
+
+// isXMLSpace reports whether b is whitespace as the XML S production defines it
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// SniffXMLEncoding determines the encoding of an XML document per XML 1.0
+// Appendix F: a BOM, then UTF-16 without a BOM recognised from the bytes of
+// "<?", then the encoding declaration. It returns nil if none of these is
+// present, in which case the document is UTF-8. A declaration that names
+// UTF-16 in an ASCII-compatible document is impossible and reported as
+// UTF-8, as browsers do.
+func SniffXMLEncoding(input []byte) *EncodingInfo {
+	if encoding, _ := DetectBOM(input); encoding != nil {
+		return encoding
+	}
+
+	switch {
+	case bytes.HasPrefix(input, []byte{0x3C, 0x00, 0x3F, 0x00}):
+		return utf16LE
+	case bytes.HasPrefix(input, []byte{0x00, 0x3C, 0x00, 0x3F}):
+		return utf16BE
+	}
+
+	label, ok := xmlDeclaredEncoding(input)
+	if !ok {
+		return nil
+	}
+	encoding := Lookup(label)
+	if encoding == utf16LE || encoding == utf16BE {
+		return UTF8
+	}
+	return encoding
+}
+
+// xmlDeclaredEncoding returns the value of the encoding pseudo-attribute of
+// the XML declaration at the start of input, if there is one
+func xmlDeclaredEncoding(input []byte) (string, bool) {
+	if !bytes.HasPrefix(input, []byte("<?xml")) || len(input) < 6 || !isXMLSpace(input[5]) {
+		return "", false
+	}
+	if len(input) > xmlDeclarationLimit {
+		input = input[:xmlDeclarationLimit]
+	}
+
+	pos := 5
+	for {
+		for pos < len(input) && isXMLSpace(input[pos]) {
+			pos++
+		}
+		if pos >= len(input) || input[pos] == '?' {
+			return "", false
+		}
+
+		start := pos
+		for pos < len(input) && input[pos] != '=' && !isXMLSpace(input[pos]) && input[pos] != '?' {
+			pos++
+		}
+		name := string(input[start:pos])
+
+		for pos < len(input) && isXMLSpace(input[pos]) {
+			pos++
+		}
+		if pos >= len(input) || input[pos] != '=' {
+			return "", false
+		}
+		pos++
+		for pos < len(input) && isXMLSpace(input[pos]) {
+			pos++
+		}
+		if pos >= len(input) || (input[pos] != '"' && input[pos] != '\'') {
+			return "", false
+		}
+
+		quote := input[pos]
+		end := bytes.IndexByte(input[pos+1:], quote)
+		if end < 0 {
+			return "", false
+		}
+		value := string(input[pos+1 : pos+1+end])
+		pos += end + 2
+
+		if name == "encoding" {
+			return value, true
+		}
+	}
+}
+
+// DecodeXML decodes an XML document with the encoding found by
+// SniffXMLEncoding, or UTF-8 if there is none. A declared encoding that
+// this package has no codec for, such as windows-1251, fails with
+// ErrUnsupportedEncoding, though the encoding is still returned.
+func DecodeXML(input []byte, errors string) (string, *EncodingInfo, error) {
+	decoded, result, err := DecodeXMLWithResult(input, errors)
+	return decoded, result.Encoding, err
+}
+
+// DecodeXMLWithResult is like DecodeXML but reports whether the encoding
+// came from a BOM, the XML declaration or the UTF-8 default. The result is
+// returned alongside ErrUnsupportedEncoding too.
+func DecodeXMLWithResult(input []byte, errors string) (string, *DecodeResult, error) {
+	if errors == "" {
+		errors = "replace"
+	}
+
+	result, remaining := sniffBOM(input)
+	if result == nil {
+		if encoding := SniffXMLEncoding(input); encoding != nil {
+			result = newDecodeResult(encoding, SourceXMLDeclaration)
+		}
+	}
+	if result == nil {
+		result = newDecodeResult(UTF8, SourceFallback)
+	}
+
+	return decodeWithResult(remaining, result, errors)
+}
+
+// CharsetReader returns a reader that decodes input from charset to UTF-8.
+// It has the signature of encoding/xml's Decoder.CharsetReader, and fails
+// with ErrUnsupportedEncoding for a charset this package has no codec for.
+func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	encoding := Lookup(charset)
+	if encoding == nil {
+		return nil, ErrUnknownEncoding
+	}
+	if !hasCodec(encoding) {
+		return nil, ErrUnsupportedEncoding
+	}
+	if encoding == UTF8 {
+		return input, nil
+	}
+	return newDecodeReader(input, newChunkDecoder(encoding, "replace")), nil
+}
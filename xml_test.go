@@ -0,0 +1,112 @@
+package webencodings
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestSniffXMLEncoding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"\xef\xbb\xbf<?xml version=\"1.0\" encoding=\"koi8-r\"?>", "utf-8"},
+		{"\xff\xfe<\x00?\x00", "utf-16le"},
+		{"\xfe\xff\x00<\x00?", "utf-16be"},
+		{"<\x00?\x00x\x00m\x00l\x00", "utf-16le"},
+		{"\x00<\x00?\x00x\x00m\x00l", "utf-16be"},
+		{`<?xml version="1.0" encoding="windows-1251"?><a/>`, "windows-1251"},
+		{`<?xml version='1.0' encoding='Shift_JIS' standalone='yes'?>`, "shift_jis"},
+		{"<?xml\tversion = \"1.0\"\r\n encoding = 'EUC-JP' ?>", "euc-jp"},
+		{`<?xml version="1.0" encoding="UTF-16"?>`, "utf-8"},
+
+		// No declared encoding
+		{``, ""},
+		{`<a/>`, ""},
+		{`<?xml version="1.0"?>`, ""},
+		{`<?xml-stylesheet href="a" encoding="big5"?>`, ""},
+		{`<?xml version="1.0" encoding="bogus"?>`, ""},
+		{`<?xml version="1.0" encoding="big5`, ""},
+		{`<?xml version=1.0 encoding="big5"?>`, ""},
+	}
+
+	for _, test := range tests {
+		encoding := SniffXMLEncoding([]byte(test.input))
+		if test.expected == "" {
+			if encoding != nil {
+				t.Errorf("SniffXMLEncoding(%q) = %v, expected nil", test.input, encoding)
+			}
+		} else if encoding == nil || encoding.Name != test.expected {
+			t.Errorf("SniffXMLEncoding(%q) = %v, expected %s", test.input, encoding, test.expected)
+		}
+	}
+}
+
+func TestDecodeXML(t *testing.T) {
+	decoded, encoding, err := DecodeXML([]byte("<?xml version=\"1.0\" encoding=\"x-user-defined\"?><a>\xf7</a>"), "")
+	if err != nil {
+		t.Fatalf("DecodeXML failed: %v", err)
+	}
+	if encoding.Name != "x-user-defined" {
+		t.Errorf("Expected x-user-defined, got %s", encoding.Name)
+	}
+	expected := "<?xml version=\"1.0\" encoding=\"x-user-defined\"?><a>\uf7f7</a>"
+	if decoded != expected {
+		t.Errorf("Expected %q, got %q", expected, decoded)
+	}
+
+	decoded, encoding, err = DecodeXML([]byte("\xef\xbb\xbf<a/>"), "")
+	if err != nil {
+		t.Fatalf("DecodeXML failed: %v", err)
+	}
+	if encoding.Name != "utf-8" || decoded != "<a/>" {
+		t.Errorf("Expected %q as utf-8, got %q as %s", "<a/>", decoded, encoding.Name)
+	}
+
+	_, encoding, err = DecodeXML([]byte("<a/>"), "")
+	if err != nil {
+		t.Fatalf("DecodeXML failed: %v", err)
+	}
+	if encoding.Name != "utf-8" {
+		t.Errorf("Expected utf-8 by default, got %s", encoding.Name)
+	}
+
+	decoded, encoding, err = DecodeXML([]byte("<?xml version=\"1.0\" encoding=\"windows-1251\"?><a>\xe0</a>"), "")
+	if err != ErrUnsupportedEncoding {
+		t.Errorf("Expected %v, got %v", ErrUnsupportedEncoding, err)
+	}
+	if encoding == nil || encoding.Name != "windows-1251" || decoded != "" {
+		t.Errorf("Expected no output as windows-1251, got %q as %v", decoded, encoding)
+	}
+}
+
+func TestCharsetReader(t *testing.T) {
+	input := "<?xml version=\"1.0\" encoding=\"x-user-defined\"?><a>b\xf7</a>"
+	decoder := xml.NewDecoder(strings.NewReader(input))
+	decoder.CharsetReader = CharsetReader
+
+	var doc struct {
+		A string `xml:",chardata"`
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatalf("xml.Decoder failed: %v", err)
+	}
+	if doc.A != "b\uf7f7" {
+		t.Errorf("Expected %q, got %q", "b\uf7f7", doc.A)
+	}
+
+	if _, err := CharsetReader("bogus", strings.NewReader("")); err != ErrUnknownEncoding {
+		t.Errorf("Expected %v, got %v", ErrUnknownEncoding, err)
+	}
+	if _, err := CharsetReader("windows-1251", strings.NewReader("")); err != ErrUnsupportedEncoding {
+		t.Errorf("Expected %v, got %v", ErrUnsupportedEncoding, err)
+	}
+
+	// encoding/xml reports the refusal instead of reading the bytes as UTF-8
+	decoder = xml.NewDecoder(strings.NewReader("<?xml version=\"1.0\" encoding=\"windows-1251\"?><a>\xe0</a>"))
+	decoder.CharsetReader = CharsetReader
+	if err := decoder.Decode(&doc); err == nil || !strings.Contains(err.Error(), ErrUnsupportedEncoding.Error()) {
+		t.Errorf("Expected %v from xml.Decoder, got %v", ErrUnsupportedEncoding, err)
+	}
+}